@@ -2,7 +2,7 @@ package main
 
 import (
 	"fmt"
-	"github.com/renevanderark/goharvest/oai"
+	"github.com/horstmumpitz/goharvest/oai"
 )
 
 func main() {
@@ -3,7 +3,7 @@ package main
 import (
 	"bufio"
 	"fmt"
-	"github.com/renevanderark/goharvest/oai"
+	"github.com/horstmumpitz/goharvest/oai"
 	"os"
 )
 
@@ -55,7 +55,6 @@ func main() {
 	// Perform GetRecord, pass dump func as callback
 	req = &oai.Request{
 		BaseUrl:        "http://services.kb.nl/mdo/oai",
-		Set:            "DTS",
 		MetadataPrefix: "dcx",
 		Verb:           "GetRecord",
 		Identifier:     "DTS:dts:7929:mpeg21",
@@ -2,7 +2,7 @@ package main
 
 import (
 	"fmt"
-	"github.com/renevanderark/goharvest/oai"
+	"github.com/horstmumpitz/goharvest/oai"
 )
 
 // Dump a snippet of the Record metadata
@@ -14,7 +14,6 @@ func dump(resp *oai.Response) {
 func getRecord(hdr *oai.Header) {
 	req := &oai.Request{
 		BaseUrl:        "http://services.kb.nl/mdo/oai",
-		Set:            "DTS",
 		MetadataPrefix: "dcx",
 		Verb:           "GetRecord",
 		Identifier:     hdr.Identifier,
@@ -0,0 +1,13 @@
+module github.com/horstmumpitz/goharvest
+
+go 1.24.0
+
+require (
+	go.etcd.io/bbolt v1.4.3
+	golang.org/x/net v0.47.0
+)
+
+require (
+	golang.org/x/sys v0.38.0 // indirect
+	golang.org/x/text v0.31.0 // indirect
+)
@@ -0,0 +1,157 @@
+package oai
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Returned by the Write of an archive writer already closed
+var ErrArchiveClosed = errors.New("oai: archive closed")
+
+// A RecordWriter streaming each record as an XML document into a tar
+// archive, as identifier.xml; Close writes the end of the archive, and
+// must be called even after a failed harvest to leave a valid archive
+type TarWriter struct {
+	tar     *tar.Writer
+	gzip    *gzip.Writer
+	entries archiveEntries
+	closed  bool
+}
+
+// A TarWriter writing a tar archive to w, which is not closed
+func NewTarWriter(w io.Writer) *TarWriter {
+	return &TarWriter{tar: tar.NewWriter(w)}
+}
+
+// A TarWriter writing a gzip-compressed tar archive (.tar.gz) to w,
+// which is not closed
+func NewTarGzWriter(w io.Writer) *TarWriter {
+	gzipWriter := gzip.NewWriter(w)
+	return &TarWriter{tar: tar.NewWriter(gzipWriter), gzip: gzipWriter}
+}
+
+// Add the record as an entry modified at its datestamp; a record harvested
+// again, as in overlapping windows, is added under a name of its own
+func (tarWriter *TarWriter) Write(ctx context.Context, record *Record) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if tarWriter.closed {
+		return ErrArchiveClosed
+	}
+	document, err := recordDocument(record)
+	if err != nil {
+		return err
+	}
+
+	err = tarWriter.tar.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     tarWriter.entries.name(record.Header.Identifier),
+		Mode:     0644,
+		Size:     int64(len(document)),
+		ModTime:  archiveModTime(record),
+		Format:   tar.FormatPAX,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = tarWriter.tar.Write(document)
+	return err
+}
+
+// Write the end of the archive, and of the gzip stream if compressed;
+// closing again does nothing
+func (tarWriter *TarWriter) Close() error {
+	if tarWriter.closed {
+		return nil
+	}
+	tarWriter.closed = true
+	err := tarWriter.tar.Close()
+	if tarWriter.gzip != nil {
+		if gzipErr := tarWriter.gzip.Close(); err == nil {
+			err = gzipErr
+		}
+	}
+	return err
+}
+
+// A RecordWriter streaming each record as a compressed XML document into
+// a zip archive, as identifier.xml; Close writes the central directory,
+// and must be called even after a failed harvest to leave a valid archive
+type ZipWriter struct {
+	zip     *zip.Writer
+	entries archiveEntries
+	closed  bool
+}
+
+// A ZipWriter writing a zip archive to w, which is not closed
+func NewZipWriter(w io.Writer) *ZipWriter {
+	return &ZipWriter{zip: zip.NewWriter(w)}
+}
+
+// Add the record as an entry modified at its datestamp; a record harvested
+// again, as in overlapping windows, is added under a name of its own
+func (zipWriter *ZipWriter) Write(ctx context.Context, record *Record) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if zipWriter.closed {
+		return ErrArchiveClosed
+	}
+	document, err := recordDocument(record)
+	if err != nil {
+		return err
+	}
+
+	entry, err := zipWriter.zip.CreateHeader(&zip.FileHeader{
+		Name:     zipWriter.entries.name(record.Header.Identifier),
+		Method:   zip.Deflate,
+		Modified: archiveModTime(record),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = entry.Write(document)
+	return err
+}
+
+// Write the central directory; closing again does nothing
+func (zipWriter *ZipWriter) Close() error {
+	if zipWriter.closed {
+		return nil
+	}
+	zipWriter.closed = true
+	return zipWriter.zip.Close()
+}
+
+// The datestamp of the record, or the current time if it has none
+func archiveModTime(record *Record) time.Time {
+	if t, err := ParseDatestamp(record.Header.DateStamp); err == nil {
+		return t
+	}
+	return time.Now().UTC()
+}
+
+// The entry names used in an archive
+type archiveEntries map[string]bool
+
+// A name for the entry of the record with the given identifier, the
+// sanitized identifier, suffixed with ~2, ~3 and so on if already used
+func (entries *archiveEntries) name(identifier string) string {
+	if *entries == nil {
+		*entries = archiveEntries{}
+	}
+	base := sanitizeFilename(identifier)
+	name := base + ".xml"
+	for n := 2; (*entries)[name]; n++ {
+		name = fmt.Sprintf("%s~%d.xml", base, n)
+	}
+	(*entries)[name] = true
+	return name
+}
@@ -0,0 +1,89 @@
+package oai
+
+import (
+	"context"
+	"time"
+)
+
+// Harvest the records of a complete OAI set page by page
+// call the callback function with the records of each page and the
+// resumptionToken leading to the next one, empty on the last page;
+// an error returned by the callback stops the harvest and is returned
+func (req *Request) HarvestBatches(callback func(records []Record, token string) error) error {
+	_, err := req.HarvestBatchesContext(context.Background(), callback)
+	return err
+}
+
+// Harvest the records of a complete OAI set page by page
+// call the callback function with the records of each page and the
+// resumptionToken leading to the next one, empty on the last page,
+// deleted records included
+// and return the Summary of the harvest and the error that ended it, if any,
+// including an error returned by the callback
+func (req *Request) HarvestBatchesContext(ctx context.Context, callback func(records []Record, token string) error) (Summary, error) {
+	req = req.Clone()
+	req.Verb = VerbListRecords
+	start, summary := time.Now(), Summary{}
+	err := req.harvest(ctx, &summary, func(resp *Response) error {
+		records := resp.ListRecords.Records
+		if req.MaxRecords > 0 && summary.Records+len(records) > req.MaxRecords {
+			records = records[:req.MaxRecords-summary.Records]
+			summary.Truncated = true
+		}
+		for i := range records {
+			summary.addHeader(&records[i].Header)
+		}
+		return callback(records, resp.ListRecords.ResumptionToken.Token)
+	})
+	err = summary.finish(start, err)
+	req.logSummary(summary, err)
+	return summary, err
+}
+
+// Where a page of a harvest stands in the list
+type PageInfo struct {
+	// The URL the page was requested with
+	URL string
+	// The number of the page, starting at 1
+	Page int
+	// The resumptionToken leading to the next page, empty on the last
+	// one, with the cursor and completeListSize if the repository gives them
+	ResumptionToken ResumptionToken
+}
+
+// Harvest the records of a complete OAI set page by page
+// call the callback function with the records of each page, which may be
+// retained, and where the page stands; an error returned by the callback
+// stops the harvest and is returned
+func (req *Request) HarvestRecordBatches(callback func(records []*Record, page PageInfo) error) error {
+	_, err := req.HarvestRecordBatchesContext(context.Background(), callback)
+	return err
+}
+
+// Harvest the records of a complete OAI set page by page
+// call the callback function with the records of each page and where the
+// page stands, deleted records included; the records are copies that may
+// be retained after the callback returns, even with ReuseResponses set
+// and return the Summary of the harvest and the error that ended it, if any,
+// including an error returned by the callback
+func (req *Request) HarvestRecordBatchesContext(ctx context.Context, callback func(records []*Record, page PageInfo) error) (Summary, error) {
+	req = req.Clone()
+	req.Verb = VerbListRecords
+	start, summary, page := time.Now(), Summary{}, 0
+	err := req.harvest(ctx, &summary, func(resp *Response) error {
+		page++
+		records := []*Record{}
+		for _, record := range resp.ListRecords.Records {
+			if req.recordLimitReached(&summary) {
+				break
+			}
+			record := record
+			summary.addHeader(&record.Header)
+			records = append(records, &record)
+		}
+		return callback(records, PageInfo{URL: resp.url, Page: page, ResumptionToken: resp.ListRecords.ResumptionToken})
+	})
+	err = summary.finish(start, err)
+	req.logSummary(summary, err)
+	return summary, err
+}
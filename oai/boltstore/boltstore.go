@@ -0,0 +1,153 @@
+// Package boltstore provides an oai.RecordStore in a bbolt database file,
+// to keep a local mirror of a repository with Request.HarvestInto
+package boltstore
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/horstmumpitz/goharvest/oai"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	// The records as XML documents by identifier
+	recordsBucket = []byte("records")
+	// The identifiers by datestamp: the keys are the datestamp in UTC,
+	// which sorts by time, a zero byte and the identifier
+	datestampsBucket = []byte("datestamps")
+)
+
+// The datestamp format of the keys of the datestamps bucket
+const keyLayout = "2006-01-02T15:04:05Z"
+
+// A RecordStore in a bbolt database; it is safe for concurrent use
+type Store struct {
+	db *bolt.DB
+}
+
+// Open the store in the database file at path, created if missing
+func Open(path string) (*Store, error) {
+	db, err := bolt.Open(path, 0644, &bolt.Options{Timeout: 10 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("boltstore: opening %s: %w", path, err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(recordsBucket); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucketIfNotExists(datestampsBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("boltstore: opening %s: %w", path, err)
+	}
+	return &Store{db: db}, nil
+}
+
+// Close the database
+func (store *Store) Close() error {
+	return store.db.Close()
+}
+
+// Store the record, replacing the one with the same identifier
+func (store *Store) Put(record *oai.Record) error {
+	var document bytes.Buffer
+	if err := record.WriteXML(&document); err != nil {
+		return err
+	}
+	identifier := record.Header.Identifier
+	return store.db.Update(func(tx *bolt.Tx) error {
+		if err := remove(tx, identifier); err != nil {
+			return err
+		}
+		if err := tx.Bucket(recordsBucket).Put([]byte(identifier), document.Bytes()); err != nil {
+			return err
+		}
+		return tx.Bucket(datestampsBucket).Put(datestampKey(record.Header.DateStamp, identifier), nil)
+	})
+}
+
+// Remove the record with the identifier, if stored
+func (store *Store) Delete(identifier string) error {
+	return store.db.Update(func(tx *bolt.Tx) error {
+		return remove(tx, identifier)
+	})
+}
+
+// The record with the identifier, and whether it is stored
+func (store *Store) Get(identifier string) (*oai.Record, bool, error) {
+	var record *oai.Record
+	err := store.db.View(func(tx *bolt.Tx) error {
+		document := tx.Bucket(recordsBucket).Get([]byte(identifier))
+		if document == nil {
+			return nil
+		}
+		var err error
+		record, err = oai.ParseRecord(bytes.NewReader(document))
+		return err
+	})
+	return record, record != nil, err
+}
+
+// Call the callback for each record with a datestamp at or after the
+// given one, in the order of their datestamps; records without a valid
+// datestamp are listed only since the zero time. The callback runs in a
+// read transaction, so it must not modify the store
+func (store *Store) IterateSince(datestamp time.Time, callback func(*oai.Record) error) error {
+	return store.db.View(func(tx *bolt.Tx) error {
+		records := tx.Bucket(recordsBucket)
+		cursor := tx.Bucket(datestampsBucket).Cursor()
+		since := []byte(datestamp.UTC().Format(keyLayout))
+		if datestamp.IsZero() {
+			since = nil
+		}
+		for key, _ := cursor.Seek(since); key != nil; key, _ = cursor.Next() {
+			separator := bytes.IndexByte(key, 0)
+			if separator < 0 {
+				continue
+			}
+			document := records.Get(key[separator+1:])
+			if document == nil {
+				continue
+			}
+			record, err := oai.ParseRecord(bytes.NewReader(document))
+			if err != nil {
+				return err
+			}
+			if err := callback(record); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Remove the record with the identifier and its datestamp key
+func remove(tx *bolt.Tx, identifier string) error {
+	records := tx.Bucket(recordsBucket)
+	document := records.Get([]byte(identifier))
+	if document == nil {
+		return nil
+	}
+	record, err := oai.ParseRecord(bytes.NewReader(document))
+	if err != nil {
+		return err
+	}
+	if err := tx.Bucket(datestampsBucket).Delete(datestampKey(record.Header.DateStamp, identifier)); err != nil {
+		return err
+	}
+	return records.Delete([]byte(identifier))
+}
+
+// The key of the identifier in the datestamps bucket; a datestamp that
+// cannot be parsed sorts as the zero time
+func datestampKey(datestamp, identifier string) []byte {
+	t, err := oai.ParseDatestamp(datestamp)
+	if err != nil {
+		t = time.Time{}
+	}
+	return []byte(t.UTC().Format(keyLayout) + "\x00" + identifier)
+}
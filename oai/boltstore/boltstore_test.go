@@ -0,0 +1,181 @@
+package boltstore
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/horstmumpitz/goharvest/oai"
+)
+
+// A Store in a temporary database, closed when the test ends
+func openStore(t *testing.T) *Store {
+	store, err := Open(filepath.Join(t.TempDir(), "records.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func record(identifier, datestamp, title string) *oai.Record {
+	return &oai.Record{
+		Header: oai.Header{Identifier: identifier, DateStamp: datestamp},
+		Metadata: oai.Metadata{Body: []byte(`<oai_dc:dc xmlns:oai_dc="http://www.openarchives.org/OAI/2.0/oai_dc/" ` +
+			`xmlns:dc="http://purl.org/dc/elements/1.1/"><dc:title>` + title + `</dc:title></oai_dc:dc>`)},
+	}
+}
+
+// The identifiers IterateSince lists since the datestamp
+func since(t *testing.T, store *Store, datestamp time.Time) []string {
+	identifiers := []string{}
+	err := store.IterateSince(datestamp, func(record *oai.Record) error {
+		identifiers = append(identifiers, record.Header.Identifier)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return identifiers
+}
+
+func TestStorePutGetDelete(t *testing.T) {
+	store := openStore(t)
+	if err := store.Put(record("oai:a", "2024-01-01T00:00:00Z", "First")); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Put(record("oai:a", "2024-03-01T00:00:00Z", "Second")); err != nil {
+		t.Fatal(err)
+	}
+
+	stored, ok, err := store.Get("oai:a")
+	if err != nil || !ok {
+		t.Fatalf("Get: %v, %v", ok, err)
+	}
+	if stored.Header.DateStamp != "2024-03-01T00:00:00Z" || !strings.Contains(string(stored.Metadata.Body), "Second") {
+		t.Errorf("stored %s %s, want the second version", stored.Header.DateStamp, stored.Metadata.Body)
+	}
+	// The datestamp of the replaced version is gone as well
+	if ids := since(t, store, time.Time{}); len(ids) != 1 {
+		t.Errorf("listed %v, want oai:a once", ids)
+	}
+
+	if err := store.Delete("oai:a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Delete("oai:missing"); err != nil {
+		t.Errorf("deleting a record not stored: %v", err)
+	}
+	if _, ok, err := store.Get("oai:a"); ok || err != nil {
+		t.Errorf("deleted record found (%v)", err)
+	}
+	if ids := since(t, store, time.Time{}); len(ids) != 0 {
+		t.Errorf("listed %v after the delete, want nothing", ids)
+	}
+}
+
+func TestStoreIterateSince(t *testing.T) {
+	store := openStore(t)
+	for _, r := range []*oai.Record{
+		record("oai:c", "2024-03-01T00:00:00Z", "C"),
+		record("oai:a", "2024-01-01", "A"),
+		record("oai:b", "2024-02-01T12:00:00Z", "B"),
+	} {
+		if err := store.Put(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if ids := strings.Join(since(t, store, time.Time{}), " "); ids != "oai:a oai:b oai:c" {
+		t.Errorf("listed %s, want them by datestamp", ids)
+	}
+	if ids := strings.Join(since(t, store, time.Date(2024, 2, 1, 12, 0, 0, 0, time.UTC)), " "); ids != "oai:b oai:c" {
+		t.Errorf("listed %s since February 1st noon, want oai:b oai:c", ids)
+	}
+
+	stop := errors.New("stop")
+	if err := store.IterateSince(time.Time{}, func(*oai.Record) error { return stop }); err != stop {
+		t.Errorf("got %v, want the error of the callback", err)
+	}
+}
+
+// A repository listing the records of the current page, one page only
+type pageServer struct{ records string }
+
+func (page *pageServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/xml")
+	body := `<OAI-PMH xmlns="http://www.openarchives.org/OAI/2.0/"><responseDate>2024-06-01T00:00:00Z</responseDate>`
+	switch r.URL.Query().Get("verb") {
+	case "Identify":
+		body += `<Identify><repositoryName>Test</repositoryName><baseURL>http://` + r.Host + `</baseURL>` +
+			`<protocolVersion>2.0</protocolVersion><earliestDatestamp>2024-01-01T00:00:00Z</earliestDatestamp>` +
+			`<deletedRecord>persistent</deletedRecord><granularity>YYYY-MM-DDThh:mm:ssZ</granularity></Identify>`
+	case "ListMetadataFormats":
+		body += `<ListMetadataFormats><metadataFormat><metadataPrefix>oai_dc</metadataPrefix></metadataFormat></ListMetadataFormats>`
+	default:
+		body += `<ListRecords>` + page.records + `</ListRecords>`
+	}
+	w.Write([]byte(body + `</OAI-PMH>`))
+}
+
+const (
+	recordV1 = `<record><header><identifier>oai:a</identifier><datestamp>2024-01-01T00:00:00Z</datestamp></header>` +
+		`<metadata><oai_dc:dc xmlns:oai_dc="http://www.openarchives.org/OAI/2.0/oai_dc/" ` +
+		`xmlns:dc="http://purl.org/dc/elements/1.1/"><dc:title>A</dc:title></oai_dc:dc></metadata></record>`
+	recordB = `<record><header><identifier>oai:b</identifier><datestamp>2024-01-02T00:00:00Z</datestamp></header>` +
+		`<metadata><oai_dc:dc xmlns:oai_dc="http://www.openarchives.org/OAI/2.0/oai_dc/" ` +
+		`xmlns:dc="http://purl.org/dc/elements/1.1/"><dc:title>B</dc:title></oai_dc:dc></metadata></record>`
+	tombstone = `<record><header status="deleted"><identifier>oai:a</identifier>` +
+		`<datestamp>2024-02-01T00:00:00Z</datestamp></header></record>`
+)
+
+func TestHarvestIntoStore(t *testing.T) {
+	page := &pageServer{records: recordV1 + recordB}
+	server := httptest.NewServer(page)
+	defer server.Close()
+	path := filepath.Join(t.TempDir(), "records.db")
+	store, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	harvest := func() oai.Summary {
+		summary, err := oai.NewRequest(server.URL, oai.WithMetadataPrefix("oai_dc")).HarvestInto(context.Background(), store)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return summary
+	}
+
+	harvest()
+	page.records = tombstone
+	harvest()
+	// A mirror replays the version from before the deletion
+	page.records = recordV1
+	if summary := harvest(); summary.Stale != 1 {
+		t.Errorf("%d stale records, want the replayed one", summary.Stale)
+	}
+
+	// What is stored survives reopening the database
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if store, err = Open(path); err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	stored, ok, err := store.Get("oai:a")
+	if err != nil || !ok || !stored.Header.IsDeleted() {
+		t.Fatalf("stored %+v (%v), want the tombstone of oai:a", stored, err)
+	}
+	if stored, _, _ := store.Get("oai:b"); stored == nil || !strings.Contains(string(stored.Metadata.Body), "<dc:title>B</dc:title>") {
+		t.Errorf("stored %+v, want oai:b with its metadata", stored)
+	}
+	if ids := strings.Join(since(t, store, time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)), " "); ids != "oai:a" {
+		t.Errorf("listed %s since January 15th, want the tombstone of oai:a", ids)
+	}
+}
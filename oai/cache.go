@@ -0,0 +1,59 @@
+package oai
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// A cache of response bodies keyed by request URL, consulted before a
+// request is sent and populated after a response was decoded, so a
+// harvest can be replayed without contacting the repository
+type Cache interface {
+	Get(url string) ([]byte, bool)
+	Set(url string, body []byte)
+}
+
+// A Cache storing each response body in a file in Dir, named after the
+// SHA-256 hash of the request URL
+type FileCache struct {
+	Dir string
+}
+
+// The file the response to the URL is stored in
+func (cache *FileCache) path(url string) string {
+	hash := sha256.Sum256([]byte(url))
+	return filepath.Join(cache.Dir, hex.EncodeToString(hash[:])+".xml")
+}
+
+// Read the cached response body for the URL
+func (cache *FileCache) Get(url string) ([]byte, bool) {
+	body, err := ioutil.ReadFile(cache.path(url))
+	return body, err == nil
+}
+
+// Store the response body for the URL; as a cache is an optimization,
+// failing to store it is not an error
+func (cache *FileCache) Set(url string, body []byte) {
+	if err := os.MkdirAll(cache.Dir, 0755); err != nil {
+		return
+	}
+
+	// Write to a temporary file first, so a reader never sees half a body
+	tmp, err := ioutil.TempFile(cache.Dir, ".partial-")
+	if err != nil {
+		return
+	}
+	_, err = tmp.Write(body)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), cache.path(url))
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+}
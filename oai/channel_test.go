@@ -0,0 +1,90 @@
+package oai
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestChannelHarvestStopsWithoutLeaks(t *testing.T) {
+	repo := newTestRepository(10, 2, 0)
+	entered, released := make(chan struct{}), make(chan struct{})
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Hold the third page until the client gives up on it
+		if strings.HasPrefix(r.URL.Query().Get("resumptionToken"), "4/") {
+			close(entered)
+			<-r.Context().Done()
+			close(released)
+			return
+		}
+		repo.ServeHTTP(w, r)
+	}))
+	var mu sync.Mutex
+	states := map[net.Conn]http.ConnState{}
+	server.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		mu.Lock()
+		defer mu.Unlock()
+		states[conn] = state
+	}
+	server.Start()
+	defer server.Close()
+
+	client := &http.Client{Transport: &http.Transport{}}
+	req := testRequest(server)
+	req.Verb = VerbListIdentifiers
+	req.Client = client
+	baseline := runtime.NumGoroutine()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	channels := []chan *Header{make(chan *Header)}
+	done := make(chan error)
+	go func() {
+		_, err := req.ChannelHarvestIdentifiersContext(ctx, channels)
+		done <- err
+	}()
+
+	// Take the first two pages, then stop while the third is requested
+	for i := 0; i < 4; i++ {
+		<-channels[0]
+	}
+	<-entered
+	cancel()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("harvest ended with %v, want context.Canceled", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("harvest did not stop after the context was cancelled")
+	}
+	select {
+	case <-released:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the request in flight was not cancelled")
+	}
+
+	client.CloseIdleConnections()
+	deadline := time.Now().Add(5 * time.Second)
+	for runtime.NumGoroutine() > baseline && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := runtime.NumGoroutine(); n > baseline {
+		t.Errorf("%d goroutines after the harvest, %d before", n, baseline)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	for _, state := range states {
+		if state == http.StateActive {
+			t.Error("a connection is still active after the harvest")
+		}
+	}
+}
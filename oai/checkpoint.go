@@ -0,0 +1,122 @@
+package oai
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// The state of a harvest after a page, from which it can be continued
+type HarvestState struct {
+	// The resumptionToken leading to the next page, empty once the
+	// harvest is complete
+	ResumptionToken string `json:"resumptionToken"`
+	// The cursor and completeListSize the repository gave with it
+	Cursor           int `json:"cursor,omitempty"`
+	CompleteListSize int `json:"completeListSize,omitempty"`
+
+	// The latest datestamp delivered so far
+	LastDatestamp time.Time `json:"lastDatestamp"`
+	// The number of pages and records harvested so far
+	Pages   int `json:"pages"`
+	Records int `json:"records"`
+
+	// When the state was saved
+	Saved time.Time `json:"saved"`
+
+	// The point up to which an IncrementalHarvester has harvested, and the
+	// records it delivered in the overlap before it, as identifier and
+	// datestamp, so they are not delivered again
+	Watermark time.Time `json:"watermark,omitempty"`
+	Seen      []string  `json:"seen,omitempty"`
+}
+
+// A store for the state of a harvest, saved after every page and loaded
+// when the harvest starts, so an interrupted harvest continues where it
+// stopped instead of starting over
+type CheckpointStore interface {
+	Save(ctx context.Context, state HarvestState) error
+	// Load the saved state, reporting whether there is one
+	Load(ctx context.Context) (HarvestState, bool, error)
+}
+
+// A CheckpointStore keeping the state as JSON in the file at Path
+type FileCheckpoint struct {
+	Path string
+}
+
+func (checkpoint *FileCheckpoint) Save(ctx context.Context, state HarvestState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first, so a crash never leaves half a state
+	tmp, err := ioutil.TempFile(filepath.Dir(checkpoint.Path), ".checkpoint-")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), checkpoint.Path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+func (checkpoint *FileCheckpoint) Load(ctx context.Context) (HarvestState, bool, error) {
+	state := HarvestState{}
+	data, err := ioutil.ReadFile(checkpoint.Path)
+	if os.IsNotExist(err) {
+		return state, false, nil
+	}
+	if err != nil {
+		return state, false, err
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return state, false, err
+	}
+	return state, true, nil
+}
+
+// Continue from the checkpoint of an unfinished harvest, if there is one,
+// returning the state to count on from
+func (req *Request) loadCheckpoint(ctx context.Context) (HarvestState, error) {
+	if req.Checkpoint == nil || req.ResumptionToken != "" {
+		return HarvestState{}, nil
+	}
+	state, ok, err := req.Checkpoint.Load(ctx)
+	if err != nil || !ok || state.ResumptionToken == "" {
+		return HarvestState{}, err
+	}
+	req.resume(state.ResumptionToken)
+	return state, nil
+}
+
+// Save the state of the harvest after a page
+func (req *Request) saveCheckpoint(ctx context.Context, resumed HarvestState, summary *Summary, pages, records int, token ResumptionToken) error {
+	if req.Checkpoint == nil {
+		return nil
+	}
+	state := HarvestState{
+		ResumptionToken:  token.Token,
+		Cursor:           token.Cursor,
+		CompleteListSize: token.CompleteListSize,
+		LastDatestamp:    resumed.LastDatestamp,
+		Pages:            resumed.Pages + pages,
+		Records:          resumed.Records + records,
+		Saved:            time.Now().UTC(),
+	}
+	if summary.LastDatestamp.After(state.LastDatestamp) {
+		state.LastDatestamp = summary.LastDatestamp
+	}
+	return req.Checkpoint.Save(ctx, state)
+}
@@ -0,0 +1,59 @@
+package oai
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+// Whether the repository advertises the compression, such as gzip,
+// in its Identify response
+func (identify *Identify) SupportsCompression(compression string) bool {
+	for _, supported := range identify.Compression {
+		if strings.EqualFold(strings.TrimSpace(supported), compression) {
+			return true
+		}
+	}
+	return false
+}
+
+// Ask for gzip compressed responses if the repository advertises
+// gzip compression in its Identify response, asked with the settings of
+// the Request
+func (req *Request) DetectCompression(ctx context.Context) error {
+	resp, err := req.withVerb(VerbIdentify).PerformContext(ctx)
+	if err != nil {
+		return err
+	}
+	req.AcceptGzip = resp.Identify.SupportsCompression("gzip")
+	return nil
+}
+
+// Decompress a response body sent with the given Content-Encoding,
+// gzip or deflate; deflate is meant to be zlib wrapped, but some servers
+// send raw deflate data instead
+func decompress(encoding string, body []byte) ([]byte, error) {
+	var reader io.ReadCloser
+	var err error
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "gzip", "x-gzip":
+		reader, err = gzip.NewReader(bytes.NewReader(body))
+	case "deflate":
+		reader, err = zlib.NewReader(bytes.NewReader(body))
+		if err != nil {
+			reader, err = flate.NewReader(bytes.NewReader(body)), nil
+		}
+	default:
+		return body, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return ioutil.ReadAll(reader)
+}
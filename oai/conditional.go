@@ -0,0 +1,71 @@
+package oai
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+)
+
+// Returned when a conditional request is answered with 304 Not Modified
+// and there is no cached response to use instead; a harvest ends without
+// error on it for the first page, as nothing changed, but fails on it for
+// a later page, whose records it cannot deliver. It is never retried
+var ErrNotModified = errors.New("oai: not modified")
+
+// A store of the ETag and Last-Modified validators of the responses by
+// request URL, sent back as If-None-Match and If-Modified-Since so an
+// unchanged response is not downloaded again
+type ValidatorStore interface {
+	Get(url string) (etag, lastModified string, ok bool)
+	Set(url, etag, lastModified string)
+}
+
+// A ValidatorStore in memory, ready to use as its zero value
+type MemoryValidatorStore struct {
+	mu         sync.Mutex
+	validators map[string][2]string
+}
+
+func (store *MemoryValidatorStore) Get(url string) (etag, lastModified string, ok bool) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	validators, ok := store.validators[url]
+	return validators[0], validators[1], ok
+}
+
+func (store *MemoryValidatorStore) Set(url, etag, lastModified string) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if store.validators == nil {
+		store.validators = map[string][2]string{}
+	}
+	store.validators[url] = [2]string{etag, lastModified}
+}
+
+// Make the request conditional on the validators stored for its URL
+func (req *Request) addValidators(httpRequest *http.Request, requestURL string) {
+	if req.Validators == nil {
+		return
+	}
+	etag, lastModified, ok := req.Validators.Get(requestURL)
+	if !ok {
+		return
+	}
+	if etag != "" {
+		httpRequest.Header.Set("If-None-Match", etag)
+	}
+	if lastModified != "" {
+		httpRequest.Header.Set("If-Modified-Since", lastModified)
+	}
+}
+
+// Store the validators of a successful response, if it has any
+func (req *Request) storeValidators(requestURL string, resp *http.Response) {
+	if req.Validators == nil {
+		return
+	}
+	etag, lastModified := resp.Header.Get("ETag"), resp.Header.Get("Last-Modified")
+	if etag != "" || lastModified != "" {
+		req.Validators.Set(requestURL, etag, lastModified)
+	}
+}
@@ -0,0 +1,65 @@
+package oai
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// Serve the testRepository with validators: the first page changes with
+// the generation, the later pages never do
+func conditionalServer(repo *testRepository, generation *int) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		etag := `"unchanged"`
+		if r.URL.Query().Get("resumptionToken") == "" {
+			etag = `"generation` + strconv.Itoa(*generation) + `"`
+		}
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		repo.ServeHTTP(w, r)
+	})
+	return mux
+}
+
+func TestNotModifiedIsNotRetried(t *testing.T) {
+	generation := 1
+	server := httptestServer(t, conditionalServer(newTestRepository(6, 3, 0), &generation))
+	req := testRequest(server)
+	req.Validators = &MemoryValidatorStore{}
+	req.MaxRetries, req.RetryDelay = 2, time.Millisecond
+
+	if summary, err := req.HarvestRecordsContext(context.Background(), func(*Record) {}); err != nil || summary.Records != 6 {
+		t.Fatalf("first harvest: %d records, %v", summary.Records, err)
+	}
+	summary, err := req.HarvestRecordsContext(context.Background(), func(*Record) {})
+	if err != nil {
+		t.Fatalf("second harvest: %v", err)
+	}
+	if summary.Requests != 1 || summary.Retries != 0 || summary.Records != 0 {
+		t.Errorf("second harvest: %d requests, %d retries, %d records, want 1, 0, 0",
+			summary.Requests, summary.Retries, summary.Records)
+	}
+}
+
+func TestNotModifiedAfterTheFirstPageFails(t *testing.T) {
+	generation := 1
+	server := httptestServer(t, conditionalServer(newTestRepository(6, 3, 0), &generation))
+	req := testRequest(server)
+	req.Validators = &MemoryValidatorStore{}
+
+	if _, err := req.HarvestRecordsContext(context.Background(), func(*Record) {}); err != nil {
+		t.Fatalf("first harvest: %v", err)
+	}
+	generation = 2
+	summary, err := req.HarvestRecordsContext(context.Background(), func(*Record) {})
+	if !errors.Is(err, ErrNotModified) {
+		t.Fatalf("got %v after %d records, want ErrNotModified", err, summary.Records)
+	}
+}
@@ -0,0 +1,34 @@
+package oai
+
+import (
+	"context"
+	"errors"
+)
+
+// Stops the identifier walk of CountRecords once the size is known
+var errCountComplete = errors.New("oai: count complete")
+
+// Count the records of the set cheaply: one ListIdentifiers request
+// answered with the completeListSize of its resumptionToken, or, when
+// the repository omits it, a walk through all the identifiers.
+// MaxRecords and MaxBatches do not apply
+func (req *Request) CountRecords(ctx context.Context) (int, error) {
+	req = req.Clone()
+	req.Verb = VerbListIdentifiers
+	req.MaxRecords, req.MaxBatches = 0, 0
+	req.Checkpoint = nil
+
+	count := 0
+	err := req.harvest(ctx, &Summary{}, func(resp *Response) error {
+		if size := resp.ListIdentifiers.ResumptionToken.CompleteListSize; size > 0 {
+			count = size
+			return errCountComplete
+		}
+		count += len(resp.ListIdentifiers.Headers)
+		return nil
+	})
+	if err != nil && err != errCountComplete {
+		return 0, err
+	}
+	return count, nil
+}
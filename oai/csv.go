@@ -0,0 +1,81 @@
+package oai
+
+import (
+	"context"
+	"encoding/csv"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Writes headers as CSV rows of identifier, datestamp, deleted and the
+// setSpecs joined by semicolons, quoted per RFC 4180 where needed, after
+// a row with the column names; call its Write method for each header
+// HarvestIdentifiers passes, and Flush when done
+type HeaderCSVWriter struct {
+	csv     *csv.Writer
+	started bool
+	err     error
+}
+
+// A HeaderCSVWriter writing to w
+func NewHeaderCSVWriter(w io.Writer) *HeaderCSVWriter {
+	return &HeaderCSVWriter{csv: csv.NewWriter(w)}
+}
+
+// Write the header as a row and return the error writing, if any; after
+// a failure, nothing more is written and Flush returns the error
+func (csvWriter *HeaderCSVWriter) Write(header *Header) error {
+	csvWriter.start()
+	if csvWriter.err != nil {
+		return csvWriter.err
+	}
+	csvWriter.err = csvWriter.csv.Write([]string{
+		header.Identifier,
+		header.DateStamp,
+		strconv.FormatBool(header.IsDeleted()),
+		strings.Join(header.SetSpec, ";"),
+	})
+	return csvWriter.err
+}
+
+// Write the buffered rows, and the row with the column names if
+// there are none, and return the first error writing, if any
+func (csvWriter *HeaderCSVWriter) Flush() error {
+	csvWriter.start()
+	csvWriter.csv.Flush()
+	if csvWriter.err != nil {
+		return csvWriter.err
+	}
+	return csvWriter.csv.Error()
+}
+
+// Write the row with the column names once
+func (csvWriter *HeaderCSVWriter) start() {
+	if csvWriter.started {
+		return
+	}
+	csvWriter.started = true
+	csvWriter.err = csvWriter.csv.Write([]string{"identifier", "datestamp", "deleted", "setSpecs"})
+}
+
+// Harvest the identifiers of a complete OAI set
+// write each Header to w as a CSV row, as written by HeaderCSVWriter
+// and return the Summary of the harvest and the error that ended it, if any,
+// including a failure to write
+func (req *Request) ExportIdentifiersCSV(ctx context.Context, w io.Writer) (Summary, error) {
+	req = req.Clone()
+	req.Verb = VerbListIdentifiers
+	start, summary := time.Now(), Summary{}
+	csvWriter := NewHeaderCSVWriter(w)
+	err := req.harvestIdentifiers(ctx, &summary, func(header *Header) error {
+		return csvWriter.Write(header)
+	})
+	if flushErr := csvWriter.Flush(); err == nil {
+		err = flushErr
+	}
+	err = summary.finish(start, err)
+	req.logSummary(summary, err)
+	return summary, err
+}
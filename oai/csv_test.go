@@ -0,0 +1,68 @@
+package oai
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestHeaderCSVWriterQuotesFields(t *testing.T) {
+	var out bytes.Buffer
+	csvWriter := NewHeaderCSVWriter(&out)
+	headers := []Header{
+		{Identifier: "oai:a", DateStamp: "2024-01-01", SetSpec: []string{"x", "y"}},
+		{Identifier: `oai:"b",c`, DateStamp: "2024-01-02T00:00:00Z", Status: "deleted"},
+	}
+	for i := range headers {
+		if err := csvWriter.Write(&headers[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := csvWriter.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	want := "identifier,datestamp,deleted,setSpecs\n" +
+		"oai:a,2024-01-01,false,x;y\n" +
+		`"oai:""b"",c",2024-01-02T00:00:00Z,true,` + "\n"
+	if out.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", out.String(), want)
+	}
+}
+
+func TestHeaderCSVWriterWritesColumnNamesWithoutRows(t *testing.T) {
+	var out bytes.Buffer
+	if err := NewHeaderCSVWriter(&out).Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "identifier,datestamp,deleted,setSpecs\n" {
+		t.Errorf("got %q, want the column names only", out.String())
+	}
+}
+
+func TestExportIdentifiersCSV(t *testing.T) {
+	server := newTestRepository(5, 2, 3).serve(t)
+	var out bytes.Buffer
+	summary, err := testRequest(server).ExportIdentifiersCSV(context.Background(), &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rows := bytes.Count(out.Bytes(), []byte("\n")); rows != 6 || summary.Records != 5 {
+		t.Errorf("%d rows, summary of %d records, want 6 rows for 5", rows, summary.Records)
+	}
+	if !bytes.Contains(out.Bytes(), []byte("oai:test:2,2024-01-01T02:00:00Z,true,even\n")) {
+		t.Errorf("deleted oai:test:2 missing from\n%s", out.String())
+	}
+}
+
+func TestExportIdentifiersCSVReturnsWriteError(t *testing.T) {
+	server := newTestRepository(5, 2, 0).serve(t)
+	summary, err := testRequest(server).ExportIdentifiersCSV(context.Background(), failingWriter{})
+	var harvestError *HarvestError
+	if !errors.As(err, &harvestError) || !errors.Is(err, errWrite) {
+		t.Fatalf("got %v, want a HarvestError of the write error", err)
+	}
+	if summary.Outcome != OutcomeAborted {
+		t.Errorf("outcome %v, want aborted", summary.Outcome)
+	}
+}
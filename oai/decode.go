@@ -0,0 +1,97 @@
+package oai
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html/charset"
+)
+
+// Decode OAI-PMH response XML, converting documents declared in another
+// encoding than UTF-8 (such as ISO-8859-1 or windows-1252) on the fly,
+// with the decoder set up by configure if it is not nil
+func decodeResponse(body []byte, configure func(*xml.Decoder)) (oaiResponse *Response, err error) {
+	oaiResponse, err = decodeNext(newDecoder(bytes.NewReader(body), configure))
+	if err == io.EOF {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return oaiResponse, err
+}
+
+// An XML decoder converting other encodings than UTF-8 to it,
+// set up by configure if it is not nil
+func newDecoder(r io.Reader, configure func(*xml.Decoder)) *xml.Decoder {
+	decoder := xml.NewDecoder(r)
+	decoder.CharsetReader = charset.NewReaderLabel
+	if configure != nil {
+		configure(decoder)
+	}
+	return decoder
+}
+
+// Decode the next response from the decoder, or return io.EOF
+// if there is none
+func decodeNext(decoder *xml.Decoder) (*Response, error) {
+	// Find the root element, to learn the namespace prefixes it declares
+	var root xml.StartElement
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			root = start
+			break
+		}
+	}
+
+	oaiResponse := newResponse()
+	if err := decoder.DecodeElement(oaiResponse, &root); err != nil {
+		return nil, err
+	}
+
+	// Metadata may use prefixes declared on the root rather than in itself
+	if namespaces := prefixDeclarations(root); len(namespaces) > 0 {
+		for i := range oaiResponse.ListRecords.Records {
+			oaiResponse.ListRecords.Records[i].Metadata.namespaces = namespaces
+		}
+		oaiResponse.GetRecord.Record.Metadata.namespaces = namespaces
+	}
+	return oaiResponse, nil
+}
+
+// The namespace prefix declarations (xmlns:prefix) of an element
+func prefixDeclarations(start xml.StartElement) []xml.Attr {
+	declarations := []xml.Attr{}
+	for _, attr := range start.Attr {
+		if attr.Name.Space == "xmlns" {
+			declarations = append(declarations, attr)
+		}
+	}
+	return declarations
+}
+
+// Decode a resumptionToken element; attributes with malformed values
+// are left at their zero value rather than failing the whole response
+func (token *ResumptionToken) UnmarshalXML(decoder *xml.Decoder, start xml.StartElement) error {
+	var element struct {
+		Token            string `xml:",chardata"`
+		CompleteListSize string `xml:"completeListSize,attr"`
+		Cursor           string `xml:"cursor,attr"`
+		ExpirationDate   string `xml:"expirationDate,attr"`
+	}
+	if err := decoder.DecodeElement(&element, &start); err != nil {
+		return err
+	}
+
+	*token = ResumptionToken{Token: strings.TrimSpace(element.Token)}
+	token.CompleteListSize, _ = strconv.Atoi(strings.TrimSpace(element.CompleteListSize))
+	token.Cursor, _ = strconv.Atoi(strings.TrimSpace(element.Cursor))
+	if element.ExpirationDate != "" {
+		token.ExpirationDate, _ = ParseDatestamp(element.ExpirationDate)
+	}
+	return nil
+}
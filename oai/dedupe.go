@@ -0,0 +1,63 @@
+package oai
+
+import (
+	"errors"
+	"time"
+)
+
+// Which of the records delivered more than once in a harvest, by
+// overlapping windows or a buggy repository, reach the record callback
+type DedupeMode int
+
+const (
+	// Deliver every record the repository returns
+	DedupeOff DedupeMode = iota
+	// Deliver the first record with an identifier and drop the others
+	DedupeFirst
+	// Deliver a record again only if its datestamp is newer than that of
+	// the one delivered before, so the newest is delivered last
+	DedupeNewest
+)
+
+// An IdentifierSet that also keeps the latest datestamp added for each
+// identifier, as DedupeNewest needs
+type DatestampSet interface {
+	IdentifierSet
+	AddDatestamp(identifier string, datestamp time.Time) error
+	Datestamp(identifier string) (datestamp time.Time, ok bool, err error)
+}
+
+// Give the Request an identifier set of its own for deduplication,
+// unless it has one or does not deduplicate
+func (req *Request) initDedupe() error {
+	if req.Dedupe == DedupeOff {
+		return nil
+	}
+	if req.DedupeSet == nil {
+		req.DedupeSet = &MemoryIdentifierSet{}
+	}
+	if _, ok := req.DedupeSet.(DatestampSet); req.Dedupe == DedupeNewest && !ok {
+		return errors.New("oai: DedupeNewest requires a DedupeSet that is a DatestampSet")
+	}
+	return nil
+}
+
+// Whether the record is a duplicate to drop, remembering it if not
+func (req *Request) duplicate(record *Record) (bool, error) {
+	identifier := record.Header.Identifier
+	if req.Dedupe == DedupeNewest {
+		set := req.DedupeSet.(DatestampSet)
+		datestamp, _ := record.Header.Datetime()
+		delivered, ok, err := set.Datestamp(identifier)
+		if err != nil || (ok && !datestamp.After(delivered)) {
+			return ok, err
+		}
+		return false, set.AddDatestamp(identifier, datestamp)
+	}
+
+	ok, err := req.DedupeSet.Contains(identifier)
+	if err != nil || ok {
+		return ok, err
+	}
+	return false, req.DedupeSet.Add(identifier)
+}
@@ -0,0 +1,30 @@
+package oai
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/horstmumpitz/goharvest/oai/oaidc"
+)
+
+// Decode the metadata of the Record as unqualified Dublin Core (oai_dc)
+func (record *Record) DublinCore() (*oaidc.DublinCore, error) {
+	dc := &oaidc.DublinCore{}
+	if err := record.DecodeMetadataInto(dc); err != nil {
+		return nil, err
+	}
+	return dc, nil
+}
+
+// Decode the setDescription of the Set as unqualified Dublin Core (oai_dc),
+// the format most repositories describe their sets in
+func (set *Set) Description() (*oaidc.DublinCore, error) {
+	if len(bytes.TrimSpace(set.SetDescription.Body)) == 0 {
+		return nil, fmt.Errorf("oai: set %s has no description", set.SetSpec)
+	}
+	dc, err := oaidc.Decode(set.SetDescription.Body)
+	if err != nil {
+		return nil, fmt.Errorf("oai: decoding description of set %s: %w", set.SetSpec, err)
+	}
+	return dc, nil
+}
@@ -0,0 +1,20 @@
+package oai
+
+import "testing"
+
+func TestRecordDublinCore(t *testing.T) {
+	resp := decodeFixture(t, "testdata/listrecords.xml")
+	dc, err := resp.ListRecords.Records[0].DublinCore()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dc.Title) != 2 || dc.Title[0] != "Measuring the speed of light" || dc.Title[1] != "Het meten van de lichtsnelheid" {
+		t.Errorf("titles %q, want the English and the Dutch one", dc.Title)
+	}
+	if len(dc.Creator) != 2 || len(dc.Identifier) != 2 {
+		t.Errorf("creators %q and identifiers %q, want two of each", dc.Creator, dc.Identifier)
+	}
+	if len(dc.Description) != 1 || dc.Description[0] != "A thesis on measuring & timing." {
+		t.Errorf("description %q", dc.Description)
+	}
+}
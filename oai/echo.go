@@ -0,0 +1,91 @@
+package oai
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Returned (wrapped) when StrictEcho is set and the request element of a
+// response does not echo the request sent, as when a caching proxy
+// serves the response to another request
+var ErrEchoMismatch = errors.New("oai: response echoes another request")
+
+// The arguments of the echoed request by their OAI-PMH name, the verb
+// included, leaving out the ones not echoed
+func (node *RequestNode) Arguments() map[string]string {
+	args := map[string]string{}
+	for name, value := range map[string]string{
+		"verb":            node.Verb,
+		"set":             node.Set,
+		"metadataPrefix":  node.MetadataPrefix,
+		"identifier":      node.Identifier,
+		"from":            node.From,
+		"until":           node.Until,
+		"resumptionToken": node.ResumptionToken,
+	} {
+		if value != "" {
+			args[name] = value
+		}
+	}
+	return args
+}
+
+// The echoed request as a URL, for debugging
+func (node *RequestNode) String() string {
+	values := url.Values{}
+	for name, value := range node.Arguments() {
+		values.Set(name, value)
+	}
+	return strings.TrimSpace(node.BaseURL) + "?" + values.Encode()
+}
+
+// Compare the request echoed in the response with the Request if
+// CheckEcho is set, logging a warning on a mismatch, or failing with
+// ErrEchoMismatch if StrictEcho is set. Arguments left out of the echo
+// are not held against it, nor is an echo after a badVerb or badArgument
+// error, which the repository must leave without arguments
+func (req *Request) checkEcho(oaiResponse *Response) error {
+	if !req.CheckEcho || oaiResponse.Error.Code == "badVerb" || oaiResponse.Error.Code == "badArgument" {
+		return nil
+	}
+
+	sent := req.arguments()
+	sent["verb"] = string(req.Verb)
+	mismatches := []string{}
+	for name, echoed := range oaiResponse.Request.Arguments() {
+		if echoed == sent[name] || sameDatestamp(echoed, sent[name], name) {
+			continue
+		}
+		mismatches = append(mismatches, fmt.Sprintf("%s %q instead of %q", name, echoed, sent[name]))
+	}
+	if len(mismatches) == 0 {
+		return nil
+	}
+	sort.Strings(mismatches)
+
+	if req.StrictEcho {
+		return fmt.Errorf("%w: %s", ErrEchoMismatch, strings.Join(mismatches, ", "))
+	}
+	req.logger().Warn("oai response echoes another request",
+		slog.String("url", oaiResponse.url), slog.String("echo", oaiResponse.Request.String()),
+		slog.String("mismatch", strings.Join(mismatches, ", ")))
+	return nil
+}
+
+// Whether the from or until arguments denote the same time, as some
+// repositories echo them in another form
+func sameDatestamp(echoed, sent, name string) bool {
+	if name != "from" && name != "until" {
+		return false
+	}
+	echoedTime, err := ParseDatestamp(echoed)
+	if err != nil {
+		return false
+	}
+	sentTime, err := ParseDatestamp(sent)
+	return err == nil && echoedTime.Equal(sentTime)
+}
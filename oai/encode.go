@@ -0,0 +1,183 @@
+package oai
+
+import (
+	"encoding/xml"
+	"io"
+	"strconv"
+)
+
+const (
+	oaiNamespace       = "http://www.openarchives.org/OAI/2.0/"
+	oaiSchemaLocation  = oaiNamespace + " http://www.openarchives.org/OAI/2.0/OAI-PMH.xsd"
+	xmlSchemaNamespace = "http://www.w3.org/2001/XMLSchema-instance"
+)
+
+// Encode the Response as a complete OAI-PMH document, holding only the
+// part matching its verb, or its error
+func (resp Response) MarshalXML(encoder *xml.Encoder, start xml.StartElement) error {
+	envelope := struct {
+		ResponseDate string      `xml:"responseDate"`
+		Request      RequestNode `xml:"request"`
+		Error        OAIError    `xml:"error"`
+
+		Identify            *Identify            `xml:"Identify,omitempty"`
+		ListMetadataFormats *ListMetadataFormats `xml:"ListMetadataFormats,omitempty"`
+		ListSets            *ListSets            `xml:"ListSets,omitempty"`
+		GetRecord           *GetRecord           `xml:"GetRecord,omitempty"`
+		ListIdentifiers     *ListIdentifiers     `xml:"ListIdentifiers,omitempty"`
+		ListRecords         *ListRecords         `xml:"ListRecords,omitempty"`
+	}{ResponseDate: resp.ResponseDate, Request: resp.Request, Error: resp.Error}
+
+	if resp.Error.Code == "" {
+		switch resp.verb() {
+		case "Identify":
+			envelope.Identify = &resp.Identify
+		case "ListMetadataFormats":
+			envelope.ListMetadataFormats = &resp.ListMetadataFormats
+		case "ListSets":
+			envelope.ListSets = &resp.ListSets
+		case "GetRecord":
+			envelope.GetRecord = &resp.GetRecord
+		case "ListIdentifiers":
+			envelope.ListIdentifiers = &resp.ListIdentifiers
+		case "ListRecords":
+			envelope.ListRecords = &resp.ListRecords
+		}
+	}
+
+	start = xml.StartElement{Name: xml.Name{Space: oaiNamespace, Local: "OAI-PMH"}}
+	declared := map[string]bool{}
+	for _, namespace := range resp.namespaces() {
+		if !declared[namespace.Name.Local] {
+			declared[namespace.Name.Local] = true
+			start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "xmlns:" + namespace.Name.Local}, Value: namespace.Value})
+		}
+	}
+	if !declared["xsi"] {
+		start.Attr = append([]xml.Attr{{Name: xml.Name{Local: "xmlns:xsi"}, Value: xmlSchemaNamespace}}, start.Attr...)
+	}
+	start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "xsi:schemaLocation"}, Value: oaiSchemaLocation})
+	return encoder.EncodeElement(envelope, start)
+}
+
+// The namespace prefixes the repository declared on the root element,
+// which the metadata of the records may use, so they are declared again
+// on the root element of the encoded Response
+func (resp *Response) namespaces() []xml.Attr {
+	namespaces := append([]xml.Attr{}, resp.GetRecord.Record.Metadata.namespaces...)
+	for _, record := range resp.ListRecords.Records {
+		namespaces = append(namespaces, record.Metadata.namespaces...)
+	}
+	return namespaces
+}
+
+// The verb of the Response: the one echoed in its request element,
+// or else the one of the first part holding content
+func (resp *Response) verb() string {
+	switch {
+	case resp.Request.Verb != "":
+		return resp.Request.Verb
+	case resp.Identify.RepositoryName != "" || resp.Identify.BaseURL != "":
+		return "Identify"
+	case len(resp.ListMetadataFormats.MetadataFormat) > 0:
+		return "ListMetadataFormats"
+	case len(resp.ListSets.Set) > 0:
+		return "ListSets"
+	case resp.GetRecord.Record.Header.Identifier != "":
+		return "GetRecord"
+	case len(resp.ListIdentifiers.Headers) > 0:
+		return "ListIdentifiers"
+	case len(resp.ListRecords.Records) > 0:
+		return "ListRecords"
+	}
+	return ""
+}
+
+// Encode the error element, omitted when there is no error
+func (oaiError OAIError) MarshalXML(encoder *xml.Encoder, start xml.StartElement) error {
+	if oaiError.Code == "" {
+		return nil
+	}
+	type plain OAIError
+	return encoder.EncodeElement(plain(oaiError), start)
+}
+
+// Encode the resumptionToken element with the attributes that are set,
+// omitted when there is no token at all
+func (token ResumptionToken) MarshalXML(encoder *xml.Encoder, start xml.StartElement) error {
+	if token == (ResumptionToken{}) {
+		return nil
+	}
+	if token.CompleteListSize > 0 {
+		start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "completeListSize"},
+			Value: strconv.Itoa(token.CompleteListSize)})
+	}
+	if token.Cursor > 0 {
+		start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "cursor"},
+			Value: strconv.Itoa(token.Cursor)})
+	}
+	if !token.ExpirationDate.IsZero() {
+		start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "expirationDate"},
+			Value: GranularitySecond.Format(token.ExpirationDate)})
+	}
+	return encoder.EncodeElement(token.Token, start)
+}
+
+// Encode a container element holding raw XML, omitted when it is empty
+func marshalInner(encoder *xml.Encoder, start xml.StartElement, body []byte) error {
+	if len(body) == 0 {
+		return nil
+	}
+	return encoder.EncodeElement(struct {
+		Body []byte `xml:",innerxml"`
+	}{body}, start)
+}
+
+// Encode the metadata element, omitted for deleted records
+func (md Metadata) MarshalXML(encoder *xml.Encoder, start xml.StartElement) error {
+	return marshalInner(encoder, start, md.Body)
+}
+
+// Encode the about element, omitted when empty
+func (ab About) MarshalXML(encoder *xml.Encoder, start xml.StartElement) error {
+	return marshalInner(encoder, start, ab.Body)
+}
+
+// Encode a description element, omitted when empty
+func (desc Description) MarshalXML(encoder *xml.Encoder, start xml.StartElement) error {
+	return marshalInner(encoder, start, desc.Body)
+}
+
+// Write the Response as an OAI-PMH XML document
+func (resp *Response) WriteXML(w io.Writer) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	return xml.NewEncoder(w).Encode(resp)
+}
+
+// Write the Record as a record element in the OAI-PMH namespace, declaring
+// the namespace prefixes its metadata uses but the repository declared
+// outside of it
+func (record *Record) WriteXML(w io.Writer) error {
+	return writeElement(w, "record", record, record.Metadata.namespaces...)
+}
+
+// Write the Header as a header element in the OAI-PMH namespace
+func (header *Header) WriteXML(w io.Writer) error {
+	return writeElement(w, "header", header)
+}
+
+// Write v as an element with the given name in the OAI-PMH namespace,
+// with the given namespace prefix declarations
+func writeElement(w io.Writer, name string, v interface{}, namespaces ...xml.Attr) error {
+	encoder := xml.NewEncoder(w)
+	start := xml.StartElement{Name: xml.Name{Space: oaiNamespace, Local: name}}
+	for _, namespace := range namespaces {
+		start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "xmlns:" + namespace.Name.Local}, Value: namespace.Value})
+	}
+	if err := encoder.EncodeElement(v, start); err != nil {
+		return err
+	}
+	return encoder.Flush()
+}
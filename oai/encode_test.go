@@ -0,0 +1,71 @@
+package oai
+
+import (
+	"bytes"
+	"io/ioutil"
+	"reflect"
+	"testing"
+)
+
+// Decode the response in the fixture file
+func decodeFixture(t *testing.T, filename string) *Response {
+	t.Helper()
+	body, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := decodeResponse(body, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestResponseRoundTrip(t *testing.T) {
+	for _, filename := range []string{"testdata/listrecords.xml", "testdata/listrecords-2.xml",
+		"testdata/listrecords-prefixes.xml", "testdata/getrecord-prefixes.xml"} {
+		resp := decodeFixture(t, filename)
+		var encoded bytes.Buffer
+		if err := resp.WriteXML(&encoded); err != nil {
+			t.Fatal(err)
+		}
+		again, err := decodeResponse(encoded.Bytes(), nil)
+		if err != nil {
+			t.Fatalf("%s: decoding the encoded response: %v\n%s", filename, err, encoded.Bytes())
+		}
+		if !reflect.DeepEqual(resp, again) {
+			t.Errorf("%s changed in the round trip:\n%#v\n%#v", filename, resp, again)
+		}
+	}
+}
+
+func TestRecordRoundTrip(t *testing.T) {
+	resp := decodeFixture(t, "testdata/listrecords.xml")
+	for i := range resp.ListRecords.Records {
+		record := &resp.ListRecords.Records[i]
+		var encoded bytes.Buffer
+		if err := record.WriteXML(&encoded); err != nil {
+			t.Fatal(err)
+		}
+		again, err := ParseRecord(&encoded)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(record, again) {
+			t.Errorf("record %s changed in the round trip:\n%#v\n%#v", record.Header.Identifier, record, again)
+		}
+	}
+}
+
+func TestDeletedHeaderWriteXML(t *testing.T) {
+	header := &Header{Status: "deleted", Identifier: "oai:test:1", DateStamp: "2024-01-01", SetSpec: []string{"a", "b"}}
+	var encoded bytes.Buffer
+	if err := header.WriteXML(&encoded); err != nil {
+		t.Fatal(err)
+	}
+	want := `<header xmlns="http://www.openarchives.org/OAI/2.0/" status="deleted"><identifier>oai:test:1</identifier>` +
+		`<datestamp>2024-01-01</datestamp><setSpec>a</setSpec><setSpec>b</setSpec></header>`
+	if encoded.String() != want {
+		t.Errorf("encoded header\n%s\nwant\n%s", encoded.String(), want)
+	}
+}
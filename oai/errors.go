@@ -0,0 +1,131 @@
+package oai
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Returned (wrapped in an *OAIError) when a list request matches no records,
+// which for an incremental harvest simply means there is nothing new
+var ErrNoRecordsMatch = errors.New("oai: noRecordsMatch")
+
+// Returned (wrapped) when StrictProtocolVersion is set and the repository
+// reports another protocol version than the Request expects
+var ErrProtocolVersion = errors.New("oai: unexpected protocol version")
+
+// Returned (wrapped in an *OAIError) when the repository has no record
+// with the requested identifier
+var ErrIDDoesNotExist = errors.New("oai: idDoesNotExist")
+
+// Returned (wrapped in an *OAIError) when the repository cannot deliver
+// the record, or any record of the list, in the requested metadataPrefix;
+// for GetRecord, try another prefix for that record
+var ErrCannotDisseminateFormat = errors.New("oai: cannotDisseminateFormat")
+
+// Returned (wrapped) when the repository hands out a resumption token
+// it handed out before in the same harvest, which would loop forever
+var ErrResumptionLoop = errors.New("oai: resumption token repeated")
+
+// The sentinel errors matching the OAI-PMH error codes
+var errorCodes = map[string]error{
+	"noRecordsMatch":          ErrNoRecordsMatch,
+	"idDoesNotExist":          ErrIDDoesNotExist,
+	"cannotDisseminateFormat": ErrCannotDisseminateFormat,
+}
+
+// Error representation of the OAI error
+func (oaiError *OAIError) Error() string {
+	message := strings.TrimSpace(oaiError.Message)
+	if message == "" {
+		return "oai: " + oaiError.Code
+	}
+	return "oai: " + oaiError.Code + ": " + message
+}
+
+// Match the OAI error against the sentinel error for its code,
+// so errors.Is(err, ErrNoRecordsMatch) works
+func (oaiError *OAIError) Is(target error) bool {
+	sentinel, ok := errorCodes[oaiError.Code]
+	return ok && sentinel == target
+}
+
+// The OAI error in this Response as an error, or nil if there is none
+func (resp *Response) Err() error {
+	if resp == nil || resp.Error.Code == "" {
+		return nil
+	}
+	oaiError := resp.Error
+	return &oaiError
+}
+
+// Returned when the repository answers with another HTTP status than 200 OK;
+// use errors.As with a *HTTPError to inspect it
+type HTTPError struct {
+	StatusCode int
+	Status     string
+	URL        string
+	// The start of the response body, which often explains the status
+	Body string
+	// The delay the repository asked for in its Retry-After header
+	RetryAfter time.Duration
+}
+
+// Error representation of the HTTP error
+func (httpError *HTTPError) Error() string {
+	return fmt.Sprintf("oai: GET %s: %s", httpError.URL, httpError.Status)
+}
+
+// Whether the status is the flow control of OAI-PMH, asking the
+// harvester to come back later, rather than a failure
+func (httpError *HTTPError) flowControl() bool {
+	return httpError.StatusCode == http.StatusServiceUnavailable ||
+		httpError.StatusCode == http.StatusTooManyRequests
+}
+
+// Create the HTTPError for the response to the request URL
+func newHTTPError(requestURL string, resp *http.Response, body []byte) *HTTPError {
+	const maxSnippet = 512
+	if len(body) > maxSnippet {
+		body = body[:maxSnippet]
+	}
+
+	return &HTTPError{
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		URL:        requestURL,
+		Body:       string(body),
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+	}
+}
+
+// Parse a Retry-After header holding either seconds or an HTTP date
+func parseRetryAfter(retryAfter string) time.Duration {
+	if seconds, err := strconv.Atoi(strings.TrimSpace(retryAfter)); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(retryAfter); err == nil {
+		return time.Until(date)
+	}
+	return 0
+}
+
+// Whether a failed request is worth retrying: it failed to get a
+// response at all, or the repository applied flow control
+func retryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, ErrNotModified) {
+		return false
+	}
+	var httpError *HTTPError
+	if errors.As(err, &httpError) {
+		return httpError.flowControl()
+	}
+	var hookError *hookError
+	return !errors.As(err, &hookError)
+}
@@ -0,0 +1,26 @@
+package oai
+
+import "context"
+
+// Harvest the records of a complete OAI set
+// call the record callback function for each Record whose Header
+// satisfies the predicate, for instance a set membership check
+func (req *Request) HarvestRecordsFiltered(predicate func(*Header) bool, callback func(*Record)) {
+	if _, err := req.HarvestRecordsFilteredContext(context.Background(), predicate, callback); err != nil {
+		panic(err)
+	}
+}
+
+// Harvest the records of a complete OAI set
+// call the record callback function for each Record whose Header
+// satisfies the predicate, as well as the RecordFilter if set
+// and return the Summary of the harvest and the error that ended it, if any;
+// the records left out are counted in Summary.Rejected, not in Records
+func (req *Request) HarvestRecordsFilteredContext(ctx context.Context, predicate func(*Header) bool, callback func(*Record)) (Summary, error) {
+	req = req.Clone()
+	filter := req.RecordFilter
+	req.RecordFilter = func(record *Record) bool {
+		return predicate(&record.Header) && (filter == nil || filter(record))
+	}
+	return req.HarvestRecordsContext(ctx, callback)
+}
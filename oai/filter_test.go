@@ -0,0 +1,33 @@
+package oai
+
+import (
+	"context"
+	"testing"
+)
+
+func TestHarvestRecordsFilteredCountsRejected(t *testing.T) {
+	server := newTestRepository(10, 4, 0).serve(t)
+	req := testRequest(server)
+	req.MaxRecords = 4
+
+	var records []*Record
+	summary, err := req.HarvestRecordsFilteredContext(context.Background(), func(header *Header) bool {
+		return header.Identifier != "oai:test:1" && header.Identifier != "oai:test:2"
+	}, func(record *Record) {
+		records = append(records, record)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := identifiers(records)
+	if len(ids) != 4 || ids[0] != "oai:test:0" || ids[1] != "oai:test:3" || ids[3] != "oai:test:5" {
+		t.Errorf("delivered %v, want 4 records without oai:test:1 and oai:test:2", ids)
+	}
+	if summary.Records != 4 || summary.Rejected != 2 || !summary.Truncated {
+		t.Errorf("summary of %d records and %d rejected, truncated %v, want 4, 2 and truncated",
+			summary.Records, summary.Rejected, summary.Truncated)
+	}
+	if req.RecordFilter != nil {
+		t.Error("the Request was changed")
+	}
+}
@@ -0,0 +1,45 @@
+package oai
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Check that the repository offers the MetadataPrefix of the Request,
+// asking it once per harvest
+func (req *Request) checkMetadataPrefix(ctx context.Context, summary *Summary, checks *preflights) error {
+	if !req.VerifyMetadataPrefix || req.MetadataPrefix == "" || req.ResumptionToken != "" {
+		return nil
+	}
+
+	prefixes, err := checks.offeredPrefixes(ctx, req, summary)
+	if err != nil {
+		return err
+	}
+	if !contains(prefixes, req.MetadataPrefix) {
+		return fmt.Errorf("oai: metadataPrefix %q is not offered by %s, which offers %s",
+			req.MetadataPrefix, req.BaseUrl, strings.Join(prefixes, ", "))
+	}
+	return nil
+}
+
+// The metadata prefixes the repository offers, asked once per harvest
+func (checks *preflights) offeredPrefixes(ctx context.Context, req *Request, summary *Summary) ([]string, error) {
+	answer := checks.of(req.BaseUrl)
+	answer.mu.Lock()
+	defer answer.mu.Unlock()
+	if answer.prefixes != nil {
+		return answer.prefixes, nil
+	}
+
+	resp, err := req.withVerb(VerbListMetadataFormats).perform(ctx, summary)
+	if err != nil {
+		return nil, err
+	}
+	answer.prefixes = []string{}
+	for _, format := range resp.ListMetadataFormats.MetadataFormat {
+		answer.prefixes = append(answer.prefixes, strings.TrimSpace(format.MetadataPrefix))
+	}
+	return answer.prefixes, nil
+}
@@ -0,0 +1,70 @@
+package oai
+
+import (
+	"context"
+	"strings"
+)
+
+// The friends description of a repository, listing the base URLs of
+// related repositories for discovery
+type FriendsDescription struct {
+	BaseURL []string `xml:"baseURL"`
+}
+
+// The friends description of the repository, if it has one
+func (identify *Identify) Friends() (*FriendsDescription, bool) {
+	friends := &FriendsDescription{}
+	if !identify.decodeDescription("friends", friends) {
+		return nil, false
+	}
+	return friends, true
+}
+
+// Crawl the friends descriptions breadth-first from the repository at
+// seedURL, up to maxDepth links away from it, and return the Identify
+// response of each repository reached by its base URL. Only a failure to
+// identify the seed repository is an error; friends that cannot be
+// identified are left out
+func DiscoverRepositories(ctx context.Context, seedURL string, maxDepth int) (map[string]*Identify, error) {
+	return (&Request{BaseUrl: seedURL}).DiscoverRepositories(ctx, maxDepth)
+}
+
+// Crawl the friends descriptions breadth-first from the repository of the
+// Request as DiscoverRepositories does, asking each repository with the
+// settings of the Request, such as its client, limiters, retries and Sign
+func (req *Request) DiscoverRepositories(ctx context.Context, maxDepth int) (map[string]*Identify, error) {
+	seedURL := strings.TrimSpace(req.BaseUrl)
+	repositories := map[string]*Identify{}
+	seen := map[string]bool{seedURL: true}
+	level := []string{seedURL}
+
+	for depth := 0; depth <= maxDepth && len(level) > 0; depth++ {
+		next := []string{}
+		for _, baseURL := range level {
+			identify := req.withVerb(VerbIdentify)
+			identify.BaseUrl = baseURL
+			resp, err := identify.PerformContext(ctx)
+			if err != nil {
+				if baseURL == seedURL || ctx.Err() != nil {
+					return repositories, err
+				}
+				continue
+			}
+			repositories[baseURL] = &resp.Identify
+
+			friends, ok := resp.Identify.Friends()
+			if !ok {
+				continue
+			}
+			for _, friend := range friends.BaseURL {
+				friend = strings.TrimSpace(friend)
+				if friend != "" && !seen[friend] {
+					seen[friend] = true
+					next = append(next, friend)
+				}
+			}
+		}
+		level = next
+	}
+	return repositories, nil
+}
@@ -0,0 +1,240 @@
+package oai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Harvest the records of a complete OAI set by listing the identifiers
+// and fetching each record with GetRecord, using the given number of workers
+// call the record callback function for each Record
+func (req *Request) HarvestViaGetRecord(workers int, callback func(*Record)) {
+	if _, err := req.HarvestViaGetRecordContext(context.Background(), workers, callback); err != nil {
+		panic(err)
+	}
+}
+
+// Harvest the records of a complete OAI set by listing the identifiers
+// and fetching each record with GetRecord, using the given number of workers,
+// a workaround for repositories whose ListRecords is slow or unreliable
+// call the record callback function for each Record, one call at a time
+// and return the Summary of the harvest and the error that ended it, if any
+//
+// Deleted records are not fetched; they are delivered with their Header
+// only, unless SkipDeleted or OnDeleted keep them from the callback. The
+// set filters, RecordFilter and Dedupe apply as they do to HarvestRecords,
+// the records of the sets filtered out are not even fetched.
+// Each GetRecord request is retried as configured by MaxRetries; the first
+// one failing anyway stops the harvest, except for an idDoesNotExist error,
+// for a record deleted since it was listed, if SkipMissing is set.
+// The records are delivered in the order they are fetched in, or in the
+// order they were listed in if OrderedDelivery is set. The records of a
+// page are all delivered before the next page is listed and the
+// Checkpoint saved, if any
+func (req *Request) HarvestViaGetRecordContext(ctx context.Context, workers int, callback func(*Record)) (Summary, error) {
+	req = req.Clone()
+	if workers < 1 {
+		workers = 1
+	}
+	start, summary := time.Now(), Summary{}
+	if err := req.initDedupe(); err != nil {
+		return summary, summary.finish(start, err)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		workerErr error
+		// The headers of the page dispatched but not yet done with, and
+		// the number of headers done with
+		inFlight sync.WaitGroup
+		done     int
+	)
+	// The records fetched ahead of their turn when OrderedDelivery is set,
+	// nil for the skipped ones, by their position in the listing
+	pending, next := map[int]*Record{}, 0
+
+	// Deliver a record fetched through the filters, with mu held
+	deliver := func(record *Record) error {
+		return req.deliverRecords(&summary, []Record{*record}, func(record *Record) error {
+			callback(record)
+			return nil
+		})
+	}
+
+	headers := make(chan listedHeader)
+	summaries := make([]Summary, workers)
+	for i := range summaries {
+		wg.Add(1)
+		go func(workerSummary *Summary) {
+			defer wg.Done()
+			for listed := range headers {
+				record, err := req.getRecord(ctx, workerSummary, listed.header)
+				if errors.Is(err, ErrIDDoesNotExist) && req.SkipMissing {
+					req.logger().Warn("oai record missing", slog.String("identifier", listed.header.Identifier))
+					record, err = nil, nil
+				}
+
+				mu.Lock()
+				switch {
+				case err != nil:
+				case workerErr != nil:
+				case req.OrderedDelivery:
+					pending[listed.position] = record
+					for record, ok := pending[next]; ok && err == nil; record, ok = pending[next] {
+						delete(pending, next)
+						next++
+						if record != nil {
+							err = deliver(record)
+						}
+					}
+				case record != nil:
+					err = deliver(record)
+				}
+				if err != nil && workerErr == nil {
+					workerErr = err
+					cancel()
+				}
+				done++
+				mu.Unlock()
+				inFlight.Done()
+			}
+		}(&summaries[i])
+	}
+
+	list := req.Clone()
+	list.Verb = VerbListIdentifiers
+	dispatched := 0
+	err := list.harvest(ctx, &summary, func(resp *Response) error {
+		for _, header := range resp.ListIdentifiers.Headers {
+			// Fetch no more records than MaxRecords leaves room for,
+			// counting those on their way
+			if req.MaxRecords > 0 {
+				mu.Lock()
+				full := summary.Records+dispatched-done >= req.MaxRecords
+				mu.Unlock()
+				if full {
+					inFlight.Wait()
+				}
+				mu.Lock()
+				limited := req.recordLimitReached(&summary)
+				mu.Unlock()
+				if limited {
+					break
+				}
+			}
+			// No use fetching a record the set filters drop
+			if !req.setsAllow(&header) {
+				mu.Lock()
+				summary.Filtered++
+				mu.Unlock()
+				continue
+			}
+			inFlight.Add(1)
+			select {
+			case headers <- listedHeader{dispatched, header}:
+				dispatched++
+			case <-ctx.Done():
+				inFlight.Done()
+				return ctx.Err()
+			}
+		}
+
+		// Move on, and save the checkpoint after the page, only once its
+		// records are delivered, so resuming after a crash loses none
+		inFlight.Wait()
+		mu.Lock()
+		defer mu.Unlock()
+		return workerErr
+	})
+	close(headers)
+	wg.Wait()
+
+	for _, workerSummary := range summaries {
+		summary.merge(workerSummary)
+	}
+	if workerErr != nil {
+		err = workerErr
+	} else if err == nil && ctx.Err() != nil {
+		err = ctx.Err()
+	}
+	err = summary.finish(start, err)
+	req.logSummary(summary, err)
+	return summary, err
+}
+
+// A header with its position in the listing
+type listedHeader struct {
+	position int
+	header   Header
+}
+
+// Fetch the record of the header with GetRecord, or make do with
+// the header itself for a deleted record
+func (req *Request) getRecord(ctx context.Context, summary *Summary, header Header) (*Record, error) {
+	if header.IsDeleted() {
+		return &Record{Header: header}, nil
+	}
+	return req.fetchRecord(ctx, summary, header.Identifier)
+}
+
+// Fetch the record with the given identifier with GetRecord
+func (req *Request) fetchRecord(ctx context.Context, summary *Summary, identifier string) (*Record, error) {
+	get := req.Clone()
+	get.Verb = VerbGetRecord
+	get.Identifier = identifier
+	get.Set, get.From, get.Until, get.ResumptionToken = "", "", "", ""
+	resp, err := get.perform(ctx, summary)
+	if err != nil {
+		return nil, err
+	}
+	if resp.GetRecord.Record.Header.Identifier == "" {
+		return nil, errors.New("oai: GetRecord response without a record for " + identifier)
+	}
+	return &resp.GetRecord.Record, nil
+}
+
+// Get a single record in the MetadataPrefix of the Request, trying the
+// candidate identifiers in order until the repository has a record for
+// one of them, for collections with inconsistent identifier schemes;
+// if none succeeds, the errors of all the attempts are returned joined
+func (req *Request) GetRecord(ctx context.Context, identifiers ...string) (*Record, error) {
+	if len(identifiers) == 0 {
+		return nil, errors.New("oai: GetRecord without an identifier")
+	}
+
+	attempts := []error{}
+	for _, identifier := range identifiers {
+		record, err := req.fetchRecord(ctx, &Summary{}, identifier)
+		if err == nil {
+			return record, nil
+		}
+		attempts = append(attempts, fmt.Errorf("identifier %s: %w", identifier, err))
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, errors.Join(attempts...)
+}
+
+// Call the callback with the record, or keep a tombstone away from it
+// if so configured; the Summary counts the record among its Records only
+// when it is delivered
+func (req *Request) deliver(summary *Summary, record *Record, callback func(*Record)) {
+	if record.Header.IsDeleted() && (req.SkipDeleted || req.OnDeleted != nil) {
+		summary.addHarvested(&record.Header)
+		if req.OnDeleted != nil {
+			req.OnDeleted(&record.Header)
+		}
+		return
+	}
+	summary.addHeader(&record.Header)
+	callback(record)
+}
@@ -0,0 +1,208 @@
+package oai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// A CheckpointStore keeping the states saved, with the number of records
+// delivered by the time each was saved
+type recordingCheckpoint struct {
+	mu        sync.Mutex
+	delivered *int
+	states    []HarvestState
+	counts    []int
+}
+
+func (checkpoint *recordingCheckpoint) Save(ctx context.Context, state HarvestState) error {
+	checkpoint.mu.Lock()
+	defer checkpoint.mu.Unlock()
+	checkpoint.states = append(checkpoint.states, state)
+	checkpoint.counts = append(checkpoint.counts, *checkpoint.delivered)
+	return nil
+}
+
+func (checkpoint *recordingCheckpoint) Load(ctx context.Context) (HarvestState, bool, error) {
+	return HarvestState{}, false, nil
+}
+
+func TestHarvestViaGetRecordCheckpointsDeliveredPages(t *testing.T) {
+	repo := newTestRepository(12, 4, 0)
+	server := httptestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("verb") == string(VerbGetRecord) {
+			time.Sleep(10 * time.Millisecond)
+		}
+		repo.ServeHTTP(w, r)
+	}))
+
+	delivered := 0
+	checkpoint := &recordingCheckpoint{delivered: &delivered}
+	req := testRequest(server)
+	req.Checkpoint = checkpoint
+	summary, err := req.HarvestViaGetRecordContext(context.Background(), 4, func(*Record) {
+		checkpoint.mu.Lock()
+		defer checkpoint.mu.Unlock()
+		delivered++
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Records != 12 || delivered != 12 {
+		t.Fatalf("%d records delivered, summary %d, want 12", delivered, summary.Records)
+	}
+	if len(checkpoint.states) != 3 {
+		t.Fatalf("%d checkpoints, want 3", len(checkpoint.states))
+	}
+	for i, state := range checkpoint.states {
+		if checkpoint.counts[i] < state.Records {
+			t.Errorf("checkpoint after %d records saved with %d delivered", state.Records, checkpoint.counts[i])
+		}
+	}
+}
+
+// A server for the repository answering GetRecord requests after the
+// delay for the identifier, calling the hook around each of them
+func getRecordServer(t *testing.T, repo *testRepository, delay func(identifier string) time.Duration, hook func(begin bool)) *httptest.Server {
+	return httptestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("verb") == string(VerbGetRecord) {
+			if hook != nil {
+				hook(true)
+				defer hook(false)
+			}
+			time.Sleep(delay(r.URL.Query().Get("identifier")))
+		}
+		repo.ServeHTTP(w, r)
+	}))
+}
+
+func TestHarvestViaGetRecordUsesTheWorkers(t *testing.T) {
+	var (
+		mu                sync.Mutex
+		active, maxActive int
+	)
+	server := getRecordServer(t, newTestRepository(20, 5, 4),
+		func(string) time.Duration { return 10 * time.Millisecond },
+		func(begin bool) {
+			mu.Lock()
+			defer mu.Unlock()
+			if begin {
+				active++
+			} else {
+				active--
+			}
+			if active > maxActive {
+				maxActive = active
+			}
+		})
+
+	delivered := map[string]int{}
+	summary, err := testRequest(server).HarvestViaGetRecordContext(context.Background(), 4, func(record *Record) {
+		delivered[record.Header.Identifier]++
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(delivered) != 20 || summary.Records != 20 || summary.Deleted != 5 {
+		t.Errorf("%d records delivered, summary of %d with %d deleted, want 20 with 5", len(delivered), summary.Records, summary.Deleted)
+	}
+	if maxActive < 2 || maxActive > 4 {
+		t.Errorf("%d GetRecord requests at a time, want 2 to 4", maxActive)
+	}
+}
+
+func TestHarvestViaGetRecordOrderedDelivery(t *testing.T) {
+	// The later records come in first
+	server := getRecordServer(t, newTestRepository(12, 4, 0), func(identifier string) time.Duration {
+		i, _ := strconv.Atoi(strings.TrimPrefix(identifier, "oai:test:"))
+		return time.Duration(12-i) * time.Millisecond
+	}, nil)
+	req := testRequest(server)
+	req.OrderedDelivery = true
+
+	var records []*Record
+	if _, err := req.HarvestViaGetRecordContext(context.Background(), 4, func(record *Record) {
+		records = append(records, record)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	for i, record := range records {
+		if want := fmt.Sprintf("oai:test:%d", i); record.Header.Identifier != want {
+			t.Fatalf("record %d is %s, want %s in %v", i, record.Header.Identifier, want, identifiers(records))
+		}
+	}
+	if len(records) != 12 {
+		t.Errorf("%d records delivered, want 12", len(records))
+	}
+}
+
+func TestHarvestViaGetRecordSkipMissing(t *testing.T) {
+	repo := newTestRepository(6, 10, 0)
+	server := httptestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Deleted since it was listed
+		if r.URL.Query().Get("identifier") == "oai:test:2" {
+			w.Write([]byte(`<OAI-PMH xmlns="http://www.openarchives.org/OAI/2.0/"><responseDate>2024-06-01T00:00:00Z</responseDate>` +
+				`<error code="idDoesNotExist">no such record</error></OAI-PMH>`))
+			return
+		}
+		repo.ServeHTTP(w, r)
+	}))
+
+	req := testRequest(server)
+	if _, err := req.HarvestViaGetRecordContext(context.Background(), 2, func(*Record) {}); !errors.Is(err, ErrIDDoesNotExist) {
+		t.Errorf("got %v, want the idDoesNotExist error", err)
+	}
+
+	req.SkipMissing = true
+	var records []*Record
+	summary, err := req.HarvestViaGetRecordContext(context.Background(), 2, func(record *Record) { records = append(records, record) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 5 || summary.Records != 5 {
+		t.Errorf("%d records delivered, summary of %d, want 5", len(records), summary.Records)
+	}
+}
+
+func TestHarvestViaGetRecordAppliesFilters(t *testing.T) {
+	repo := newTestRepository(10, 4, 0)
+	// Listed twice by a buggy repository
+	repo.records = append(repo.records, repo.records[1])
+	server := repo.serve(t)
+
+	req := testRequest(server)
+	req.FilterSets([]string{"odd"}, nil)
+	req.RecordFilter = func(record *Record) bool { return record.Header.Identifier != "oai:test:3" }
+	req.Dedupe = DedupeFirst
+	var records []*Record
+	summary, err := req.HarvestViaGetRecordContext(context.Background(), 3, func(record *Record) { records = append(records, record) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 4 || summary.Filtered != 5 || summary.Rejected != 1 || summary.Duplicates != 1 {
+		t.Errorf("delivered %v, %d filtered, %d rejected, %d duplicates, want 4, 5, 1 and 1",
+			identifiers(records), summary.Filtered, summary.Rejected, summary.Duplicates)
+	}
+	for _, query := range repo.served() {
+		if query.Get("verb") == string(VerbGetRecord) && query.Get("identifier") == "oai:test:0" {
+			t.Error("record of a set filtered out fetched")
+		}
+	}
+
+	req.MaxRecords = 2
+	records = nil
+	summary, err = req.HarvestViaGetRecordContext(context.Background(), 3, func(record *Record) { records = append(records, record) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := identifiers(records); len(ids) != 2 || ids[0] != "oai:test:1" || ids[1] != "oai:test:5" || !summary.Truncated {
+		t.Errorf("delivered %v, truncated %v, want oai:test:1 and 5 and truncated", ids, summary.Truncated)
+	}
+}
@@ -0,0 +1,123 @@
+package oai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Harvests several repositories at once with shared settings, which
+// apply to each Request that does not set its own
+type Harvester struct {
+	Client          *http.Client
+	MaxRetries      int
+	RetryDelay      time.Duration
+	RetryJitter     Jitter
+	RateLimiter     *RateLimiter
+	HostRateLimiter *HostRateLimiter
+	Logger          *slog.Logger
+
+	// How many repositories are harvested at the same time, 1 if not set
+	Concurrency int
+}
+
+// The Request with the shared settings of the Harvester filled in,
+// checking the repository with the checks of the run
+func (harvester *Harvester) apply(req *Request, checks *preflights) *Request {
+	req = req.Clone()
+	req.preflights = checks
+	if req.Client == nil {
+		req.Client = harvester.Client
+	}
+	if req.MaxRetries == 0 {
+		req.MaxRetries = harvester.MaxRetries
+	}
+	if req.RetryDelay == 0 {
+		req.RetryDelay = harvester.RetryDelay
+	}
+	if req.RetryJitter == JitterNone {
+		req.RetryJitter = harvester.RetryJitter
+	}
+	if req.RateLimiter == nil {
+		req.RateLimiter = harvester.RateLimiter
+	}
+	if req.HostRateLimiter == nil {
+		req.HostRateLimiter = harvester.HostRateLimiter
+	}
+	if req.Logger == nil {
+		req.Logger = harvester.Logger
+	}
+	return req
+}
+
+// Harvest the records of each Request
+// call the record callback function for each Record with the base URL
+// it was harvested from, and return the errors of the repositories that
+// failed joined together
+func (harvester *Harvester) HarvestAll(requests []*Request, callback func(base string, record *Record)) error {
+	_, err := harvester.HarvestAllContext(context.Background(), requests, callback)
+	return err
+}
+
+// Harvest the records of each Request, Concurrency repositories at a time
+// call the record callback function for each Record with the base URL
+// it was harvested from, one call at a time
+// and return the Summary of each harvest, in the order of the requests,
+// and the errors of the repositories that failed joined together; a
+// failing repository does not stop the others
+func (harvester *Harvester) HarvestAllContext(ctx context.Context, requests []*Request, callback func(base string, record *Record)) ([]Summary, error) {
+	var mu sync.Mutex
+	summaries, errs := harvester.run(ctx, requests, func(ctx context.Context, _ int, req *Request) (Summary, error) {
+		return req.HarvestRecordsContext(ctx, func(record *Record) {
+			mu.Lock()
+			defer mu.Unlock()
+			callback(req.BaseUrl, record)
+		})
+	})
+	for i, err := range errs {
+		if err != nil {
+			errs[i] = fmt.Errorf("%s: %w", requests[i].BaseUrl, err)
+		}
+	}
+	return summaries, errors.Join(errs...)
+}
+
+// Run the harvest of each Request, Concurrency repositories at a time,
+// passing the index of the Request in requests along, and return the
+// Summary and the error of each, in the order of the requests
+func (harvester *Harvester) run(ctx context.Context, requests []*Request, harvest func(ctx context.Context, i int, req *Request) (Summary, error)) ([]Summary, []error) {
+	concurrency := harvester.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	// The repositories are checked once for all the requests of the run
+	checks := &preflights{}
+
+	var wg sync.WaitGroup
+	summaries := make([]Summary, len(requests))
+	errs := make([]error, len(requests))
+	slots := make(chan struct{}, concurrency)
+	for i, req := range requests {
+		wg.Add(1)
+		go func(i int, req *Request) {
+			defer wg.Done()
+			select {
+			case slots <- struct{}{}:
+				defer func() { <-slots }()
+			case <-ctx.Done():
+				errs[i] = ctx.Err()
+				return
+			}
+
+			summaries[i], errs[i] = harvest(ctx, i, req)
+		}(i, harvester.apply(req, checks))
+	}
+	wg.Wait()
+
+	return summaries, errs
+}
@@ -0,0 +1,63 @@
+package oai
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// The context key marking retry attempts
+type retryKey struct{}
+
+// Whether the HTTP request passed to the OnRequest or OnResponse hook is
+// a retry of a request that failed before
+func IsRetry(httpRequest *http.Request) bool {
+	attempt, _ := httpRequest.Context().Value(retryKey{}).(int)
+	return attempt > 0
+}
+
+// Mark the context of the given attempt at a request, counting from 0
+func withAttempt(ctx context.Context, attempt int) context.Context {
+	if attempt == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, retryKey{}, attempt)
+}
+
+// An error returned by the BeforeRequest hook, aborting the request
+type hookError struct {
+	err error
+}
+
+func (hookError *hookError) Error() string { return "oai: BeforeRequest: " + hookError.err.Error() }
+
+func (hookError *hookError) Unwrap() error { return hookError.err }
+
+// Call the OnRequest hook, if any, recovering from a panic in it
+func (req *Request) onRequest(summary *Summary, httpRequest *http.Request) {
+	if req.OnRequest != nil {
+		req.callHook(summary, "OnRequest", func() { req.OnRequest(httpRequest) })
+	}
+}
+
+// Call the OnResponse hook, if any, recovering from a panic in it
+func (req *Request) onResponse(summary *Summary, httpRequest *http.Request, resp *http.Response, duration time.Duration) {
+	if req.OnResponse != nil {
+		req.callHook(summary, "OnResponse", func() { req.OnResponse(httpRequest, resp, duration) })
+	}
+}
+
+// Call the hook, reporting a panic in it in the summary and the log
+// instead of letting it break the harvest
+func (req *Request) callHook(summary *Summary, name string, hook func()) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err := fmt.Errorf("oai: %s hook panicked: %v", name, recovered)
+			req.logger().Error("oai hook panicked", slog.String("hook", name), slog.Any("error", err))
+			summary.HookErrors = append(summary.HookErrors, err)
+		}
+	}()
+	hook()
+}
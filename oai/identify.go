@@ -0,0 +1,169 @@
+package oai
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"net/mail"
+	"strings"
+)
+
+// The oai-identifier description of a repository, describing the
+// format of the OAI identifiers it issues
+type OAIIdentifierDescription struct {
+	Scheme               string `xml:"scheme"`
+	RepositoryIdentifier string `xml:"repositoryIdentifier"`
+	Delimiter            string `xml:"delimiter"`
+	SampleIdentifier     string `xml:"sampleIdentifier"`
+}
+
+// Split an identifier of the oai-identifier scheme, such as
+// oai:arXiv.org:hep-th/9901001, into the repository identifier and the
+// local identifier, reporting whether it follows the described format
+func (desc *OAIIdentifierDescription) Split(identifier string) (repositoryIdentifier, localIdentifier string, ok bool) {
+	delimiter := desc.Delimiter
+	if delimiter == "" {
+		delimiter = ":"
+	}
+	prefix := desc.Scheme + delimiter + desc.RepositoryIdentifier + delimiter
+	if !strings.HasPrefix(identifier, prefix) || len(identifier) == len(prefix) {
+		return "", "", false
+	}
+	return desc.RepositoryIdentifier, identifier[len(prefix):], true
+}
+
+// A text with an optional URL pointing to the full text, as used by the
+// eprints description
+type TextURL struct {
+	URL  string `xml:"URL"`
+	Text string `xml:"text"`
+}
+
+// The eprints description of a repository, describing its content and
+// its metadata, data and submission policies
+type EPrintsDescription struct {
+	Content          TextURL  `xml:"content"`
+	MetadataPolicy   TextURL  `xml:"metadataPolicy"`
+	DataPolicy       TextURL  `xml:"dataPolicy"`
+	SubmissionPolicy TextURL  `xml:"submissionPolicy"`
+	Comment          []string `xml:"comment"`
+}
+
+// The branding description of a repository, with an icon for its
+// collection and stylesheets for rendering its metadata, either of
+// which may be missing
+type BrandingDescription struct {
+	CollectionIcon    *CollectionIcon     `xml:"collectionIcon"`
+	MetadataRendering []MetadataRendering `xml:"metadataRendering"`
+}
+
+// The icon of a collection, with the link and title to show with it
+type CollectionIcon struct {
+	URL    string `xml:"url"`
+	Link   string `xml:"link"`
+	Title  string `xml:"title"`
+	Width  int    `xml:"width"`
+	Height int    `xml:"height"`
+}
+
+// The URL of a stylesheet rendering metadata of the given namespace
+type MetadataRendering struct {
+	MetadataNamespace string `xml:"metadataNamespace,attr"`
+	MimeType          string `xml:"mimeType,attr"`
+	URL               string `xml:",chardata"`
+}
+
+// The name of the first element in an XML fragment
+func rootElement(body []byte) (xml.Name, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return xml.Name{}, io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return xml.Name{}, err
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			return start.Name, nil
+		}
+	}
+}
+
+// The name of the element contained in the description
+func (desc Description) Name() string {
+	name, _ := rootElement(desc.Body)
+	return name.Local
+}
+
+// Decode the element contained in the description into v
+func (desc Description) Decode(v interface{}) error {
+	return xml.Unmarshal(desc.Body, v)
+}
+
+// The first valid administrator e-mail address of the repository,
+// or an empty string if it gives none
+func (identify *Identify) PrimaryEmail() string {
+	if emails := identify.AdminEmails(); len(emails) > 0 {
+		return emails[0]
+	}
+	return ""
+}
+
+// The valid administrator e-mail addresses of the repository, trimmed
+// and stripped of any display name, leaving out the malformed ones
+func (identify *Identify) AdminEmails() []string {
+	emails := []string{}
+	for _, email := range identify.AdminEmail {
+		address, err := mail.ParseAddress(strings.TrimSpace(email))
+		if err == nil {
+			emails = append(emails, address.Address)
+		}
+	}
+	return emails
+}
+
+// Find the first description containing an element with the given
+// (local) name, such as oai-identifier, eprints, friends or branding
+func (identify *Identify) FindDescription(name string) (Description, bool) {
+	for _, desc := range identify.Description {
+		if desc.Name() == name {
+			return desc, true
+		}
+	}
+	return Description{}, false
+}
+
+// The oai-identifier description of the repository, if it has one
+func (identify *Identify) OAIIdentifier() (*OAIIdentifierDescription, bool) {
+	oaiIdentifier := &OAIIdentifierDescription{}
+	if !identify.decodeDescription("oai-identifier", oaiIdentifier) {
+		return nil, false
+	}
+	return oaiIdentifier, true
+}
+
+// The eprints description of the repository, if it has one
+func (identify *Identify) EPrints() (*EPrintsDescription, bool) {
+	eprints := &EPrintsDescription{}
+	if !identify.decodeDescription("eprints", eprints) {
+		return nil, false
+	}
+	return eprints, true
+}
+
+// The branding description of the repository, if it has one
+func (identify *Identify) Branding() (*BrandingDescription, bool) {
+	branding := &BrandingDescription{}
+	if !identify.decodeDescription("branding", branding) {
+		return nil, false
+	}
+	return branding, true
+}
+
+// Decode the description with the given name into v,
+// reporting whether it was found and could be decoded
+func (identify *Identify) decodeDescription(name string, v interface{}) bool {
+	desc, ok := identify.FindDescription(name)
+	return ok && desc.Decode(v) == nil
+}
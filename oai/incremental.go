@@ -0,0 +1,83 @@
+package oai
+
+import (
+	"context"
+	"time"
+)
+
+// How far an incremental harvest got: the latest datestamp it saw, and the
+// identifiers of the records carrying exactly that datestamp, which the
+// next incremental harvest skips. Keep it between runs, for instance as
+// JSON; the zero HighWater harvests all
+type HighWater struct {
+	Datestamp   time.Time `json:"datestamp"`
+	Identifiers []string  `json:"identifiers,omitempty"`
+}
+
+// Harvest the records changed since the last run, calling the record
+// callback function for each Record, and return the new HighWater to pass
+// to the next incremental harvest.
+//
+// Repositories differ in whether they treat from as inclusive, so the from
+// argument is set BoundaryOverlap (one second by default) before the last
+// datestamp, truncated to the granularity of the Request, and no change is
+// ever missed. The records the overlap brings in that are older than the
+// last datestamp were delivered by the previous run and are skipped, as
+// are the ones carrying the last datestamp that the previous run delivered
+// and identifiers delivered before in this run, so each change is delivered
+// once. When the harvest fails, last is returned along with the error, and
+// when it stops at MaxRecords or MaxBatches, last is returned as well
+func (req *Request) IncrementalHarvest(callback func(*Record), last HighWater) (HighWater, error) {
+	req = req.Clone()
+	req.From = ""
+	overlap := req.BoundaryOverlap
+	switch {
+	case overlap == 0:
+		overlap = time.Second
+	case overlap < 0:
+		overlap = 0
+	}
+	if !last.Datestamp.IsZero() {
+		req.SetFrom(last.Datestamp.Add(-overlap))
+	}
+	boundary, _ := ParseDatestamp(req.Granularity.Format(last.Datestamp))
+
+	delivered := map[string]bool{}
+	for _, identifier := range last.Identifiers {
+		delivered[identifier] = true
+	}
+	seen := map[string]bool{}
+	next := HighWater{Datestamp: last.Datestamp, Identifiers: append([]string(nil), last.Identifiers...)}
+	summary, err := req.HarvestRecordsContext(context.Background(), func(record *Record) {
+		identifier := record.Header.Identifier
+		if seen[identifier] {
+			return
+		}
+		seen[identifier] = true
+
+		datestamp, err := record.Header.Datetime()
+		if err == nil {
+			if !last.Datestamp.IsZero() && datestamp.Before(boundary) {
+				return
+			}
+			if datestamp.Equal(last.Datestamp) && delivered[identifier] {
+				return
+			}
+			switch {
+			case datestamp.After(next.Datestamp):
+				next = HighWater{Datestamp: datestamp, Identifiers: []string{identifier}}
+			case datestamp.Equal(next.Datestamp):
+				next.Identifiers = append(next.Identifiers, identifier)
+			}
+		}
+		callback(record)
+	})
+
+	// Records are not ordered by datestamp, so only a complete harvest
+	// may advance the high-water mark: not a failed one, nor one cut short
+	// by MaxRecords or MaxBatches
+	if err != nil || summary.Truncated {
+		return last, err
+	}
+	return next, nil
+}
@@ -0,0 +1,136 @@
+package oai
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIncrementalHarvestDeliversBoundaryRecordsOnce(t *testing.T) {
+	repo := newTestRepository(4, 10, 0)
+	server := repo.serve(t)
+	req := testRequest(server)
+
+	var delivered []*Record
+	collect := func(record *Record) { delivered = append(delivered, record) }
+	high, err := req.IncrementalHarvest(collect, HighWater{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(delivered) != 4 || !high.Datestamp.Equal(time.Date(2024, 1, 1, 3, 0, 0, 0, time.UTC)) {
+		t.Fatalf("%d records and high water %v, want 4 and the last datestamp", len(delivered), high.Datestamp)
+	}
+	if len(high.Identifiers) != 1 || high.Identifiers[0] != "oai:test:3" {
+		t.Errorf("boundary identifiers %v, want oai:test:3", high.Identifiers)
+	}
+
+	// A record added with the boundary datestamp is new, the one delivered
+	// with it before is not
+	added := repo.records[3]
+	added.identifier = "oai:test:added"
+	repo.records = append(repo.records, added)
+
+	delivered = nil
+	next, err := req.IncrementalHarvest(collect, high)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := identifiers(delivered); len(ids) != 1 || ids[0] != "oai:test:added" {
+		t.Errorf("second run delivered %v, want only oai:test:added", ids)
+	}
+	if !next.Datestamp.Equal(high.Datestamp) || len(next.Identifiers) != 2 {
+		t.Errorf("second high water %v, want both boundary records", next)
+	}
+
+	delivered = nil
+	if _, err := req.IncrementalHarvest(collect, next); err != nil {
+		t.Fatal(err)
+	}
+	if len(delivered) != 0 {
+		t.Errorf("third run delivered %v, want nothing", identifiers(delivered))
+	}
+}
+
+func TestTruncatedIncrementalHarvestKeepsTheHighWater(t *testing.T) {
+	repo := newTestRepository(4, 10, 0)
+	server := repo.serve(t)
+	req := testRequest(server)
+	req.MaxRecords = 2
+
+	var delivered []*Record
+	high, err := req.IncrementalHarvest(func(record *Record) { delivered = append(delivered, record) }, HighWater{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(delivered) != 2 {
+		t.Errorf("delivered %d records, want 2", len(delivered))
+	}
+	if !high.Datestamp.IsZero() || len(high.Identifiers) != 0 {
+		t.Errorf("high water %v after a truncated harvest, want the zero HighWater", high)
+	}
+}
+
+func TestIncrementalHarvestBoundaries(t *testing.T) {
+	at := func(hour int) time.Time { return time.Date(2024, 1, 1, hour, 0, 0, 0, time.UTC) }
+	for _, test := range []struct {
+		name    string
+		overlap time.Duration
+		last    HighWater
+		want    []string
+	}{
+		{"a record on the boundary the previous run missed", 0,
+			HighWater{Datestamp: at(2)}, []string{"oai:test:2", "oai:test:3"}},
+		{"a record on the boundary the previous run delivered", 0,
+			HighWater{Datestamp: at(2), Identifiers: []string{"oai:test:2"}}, []string{"oai:test:3"}},
+		{"records inside the overlap the previous run delivered", 2 * time.Hour,
+			HighWater{Datestamp: at(2), Identifiers: []string{"oai:test:2"}}, []string{"oai:test:3"}},
+		{"no overlap at all", -1,
+			HighWater{Datestamp: at(2), Identifiers: []string{"oai:test:2"}}, []string{"oai:test:3"}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			repo := newTestRepository(4, 10, 0)
+			server := repo.serve(t)
+			req := testRequest(server)
+			req.BoundaryOverlap = test.overlap
+
+			var delivered []*Record
+			high, err := req.IncrementalHarvest(func(record *Record) { delivered = append(delivered, record) }, test.last)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ids := identifiers(delivered); strings.Join(ids, " ") != strings.Join(test.want, " ") {
+				t.Errorf("delivered %v, want %v", ids, test.want)
+			}
+			if !high.Datestamp.Equal(at(3)) || len(high.Identifiers) != 1 || high.Identifiers[0] != "oai:test:3" {
+				t.Errorf("high water %v, want oai:test:3 at %v", high, at(3))
+			}
+		})
+	}
+}
+
+func TestIncrementalHarvestResumesAfterTruncation(t *testing.T) {
+	repo := newTestRepository(6, 2, 0)
+	server := repo.serve(t)
+	req := testRequest(server)
+	req.MaxBatches = 1
+
+	var delivered []*Record
+	collect := func(record *Record) { delivered = append(delivered, record) }
+	high, err := req.IncrementalHarvest(collect, HighWater{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(delivered) != 2 || !high.Datestamp.IsZero() {
+		t.Fatalf("%d records and high water %v after one batch, want 2 and the zero HighWater", len(delivered), high)
+	}
+
+	// The next run starts over from the same mark, so nothing is lost
+	req.MaxBatches = 0
+	delivered = nil
+	if high, err = req.IncrementalHarvest(collect, high); err != nil {
+		t.Fatal(err)
+	}
+	if len(delivered) != 6 || !high.Datestamp.Equal(time.Date(2024, 1, 1, 5, 0, 0, 0, time.UTC)) {
+		t.Errorf("%d records and high water %v, want all 6 and the last datestamp", len(delivered), high.Datestamp)
+	}
+}
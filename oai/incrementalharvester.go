@@ -0,0 +1,115 @@
+package oai
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// Harvests the records changed since its last successful run, keeping
+// the watermark in a CheckpointStore: each run harvests from the watermark
+// (minus the Overlap) on, and only when it completes does the watermark
+// advance, to the responseDate of the first page, the repository's own
+// clock. Records of the overlap delivered by the previous runs are not
+// delivered again, as long as their datestamp has not changed
+type IncrementalHarvester struct {
+	Request *Request
+	// The store of the watermark; not to be shared with Request.Checkpoint
+	Store CheckpointStore
+	// How far before the watermark each run starts, as a margin for
+	// records a repository datestamps late
+	Overlap time.Duration
+}
+
+// Harvest the records changed since the last successful run
+// call the record callback function for each Record
+// and return the Summary of the run and the error that ended it, if any,
+// leaving the watermark where it was on error or when truncated
+func (harvester *IncrementalHarvester) Run(ctx context.Context, callback func(*Record)) (Summary, error) {
+	start, summary := time.Now(), Summary{}
+	state, _, err := harvester.Store.Load(ctx)
+	if err != nil {
+		return summary, summary.finish(start, err)
+	}
+
+	req := harvester.Request.Clone()
+	req.Verb = VerbListRecords
+	req.From, req.ResumptionToken, req.Checkpoint = "", "", nil
+	if !state.Watermark.IsZero() {
+		req.SetFrom(state.Watermark.Add(-harvester.Overlap))
+	}
+
+	delivered := map[string]bool{}
+	for _, key := range state.Seen {
+		delivered[key] = true
+	}
+	if err := req.initDedupe(); err != nil {
+		return summary, summary.finish(start, err)
+	}
+	watermark, overlap, pages := time.Time{}, []string{}, 0
+	err = req.harvest(ctx, &summary, func(resp *Response) error {
+		pages++
+		if watermark.IsZero() {
+			watermark, _ = resp.ResponseTime()
+		}
+		fresh := []Record{}
+		for _, record := range resp.ListRecords.Records {
+			if !delivered[record.Header.Identifier+" "+record.Header.DateStamp] {
+				fresh = append(fresh, record)
+			}
+		}
+		return req.deliverRecords(&summary, fresh, func(record *Record) error {
+			key := record.Header.Identifier + " " + record.Header.DateStamp
+			if harvester.inOverlap(key, watermark, state.Watermark) {
+				overlap = append(overlap, key)
+			}
+			callback(record)
+			return nil
+		})
+	})
+
+	// A run cut short by MaxRecords or MaxBatches leaves the watermark
+	// where it was, so the next run picks up the records it left out
+	if err == nil && !summary.Truncated {
+		// Without a response to take the date from, as when no records
+		// match, the watermark stays where it was: the local clock may
+		// well be ahead of the repository's
+		if watermark.IsZero() {
+			watermark = state.Watermark
+		}
+		// The records the previous runs delivered stay seen as long as
+		// they are inside the overlap of the next run
+		seen, kept := []string{}, map[string]bool{}
+		for _, key := range append(overlap, state.Seen...) {
+			if !kept[key] && harvester.inOverlap(key, watermark, time.Time{}) {
+				kept[key] = true
+				seen = append(seen, key)
+			}
+		}
+		err = harvester.Store.Save(ctx, HarvestState{
+			LastDatestamp: summary.LastDatestamp,
+			Pages:         pages,
+			Records:       summary.Records,
+			Saved:         time.Now().UTC(),
+			Watermark:     watermark,
+			Seen:          seen,
+		})
+	}
+	err = summary.finish(start, err)
+	req.logSummary(summary, err)
+	return summary, err
+}
+
+// Whether the record of the key, its identifier and datestamp, falls inside
+// the overlap of a run starting at the watermark, or, while the watermark
+// is not known yet, at the fallback
+func (harvester *IncrementalHarvester) inOverlap(key string, watermark, fallback time.Time) bool {
+	if watermark.IsZero() {
+		watermark = fallback
+	}
+	if harvester.Overlap <= 0 || watermark.IsZero() {
+		return false
+	}
+	datestamp, err := ParseDatestamp(key[strings.LastIndex(key, " ")+1:])
+	return err == nil && !datestamp.Before(watermark.Add(-harvester.Overlap))
+}
@@ -0,0 +1,98 @@
+package oai
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestIncrementalHarvesterKeepsWatermarkWhenTruncated(t *testing.T) {
+	server := newTestRepository(10, 3, 0).serve(t)
+	req := testRequest(server)
+	req.MaxRecords = 4
+	harvester := &IncrementalHarvester{Request: req, Store: &FileCheckpoint{Path: filepath.Join(t.TempDir(), "state.json")}}
+
+	summary, err := harvester.Run(context.Background(), func(*Record) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !summary.Truncated {
+		t.Fatal("run not truncated")
+	}
+	if _, ok, err := harvester.Store.Load(context.Background()); ok || err != nil {
+		t.Fatalf("watermark saved after a truncated run (%v)", err)
+	}
+
+	req.MaxRecords = 0
+	delivered := 0
+	if _, err := harvester.Run(context.Background(), func(*Record) { delivered++ }); err != nil {
+		t.Fatal(err)
+	}
+	if delivered != 10 {
+		t.Errorf("complete run delivered %d records, want 10", delivered)
+	}
+	if _, ok, _ := harvester.Store.Load(context.Background()); !ok {
+		t.Error("watermark not saved after a complete run")
+	}
+}
+
+func TestIncrementalHarvesterAppliesFilters(t *testing.T) {
+	server := newTestRepository(10, 3, 0).serve(t)
+	req := testRequest(server)
+	req.FilterSets([]string{"odd"}, nil)
+	req.RecordFilter = func(record *Record) bool { return record.Header.Identifier != "oai:test:3" }
+	harvester := &IncrementalHarvester{Request: req, Store: &FileCheckpoint{Path: filepath.Join(t.TempDir(), "state.json")}}
+
+	var records []*Record
+	summary, err := harvester.Run(context.Background(), func(record *Record) { records = append(records, record) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 4 || summary.Filtered != 5 || summary.Rejected != 1 {
+		t.Errorf("delivered %v, %d filtered, %d rejected, want 4, 5 and 1",
+			identifiers(records), summary.Filtered, summary.Rejected)
+	}
+}
+
+func TestIncrementalHarvesterRemembersTheOverlapAcrossRuns(t *testing.T) {
+	server := newTestRepository(10, 3, 0).serve(t)
+	harvester := &IncrementalHarvester{
+		Request: testRequest(server),
+		Store:   &FileCheckpoint{Path: filepath.Join(t.TempDir(), "state.json")},
+		// The test repository answers with a responseDate months after
+		// its records, this puts them all inside the overlap
+		Overlap: 365 * 24 * time.Hour,
+	}
+
+	for run, want := range []int{10, 0, 0} {
+		delivered := 0
+		if _, err := harvester.Run(context.Background(), func(*Record) { delivered++ }); err != nil {
+			t.Fatal(err)
+		}
+		if delivered != want {
+			t.Errorf("run %d delivered %d records, want %d", run+1, delivered, want)
+		}
+		if state, _, _ := harvester.Store.Load(context.Background()); len(state.Seen) != 10 {
+			t.Errorf("run %d kept %d records seen, want 10", run+1, len(state.Seen))
+		}
+	}
+}
+
+func TestIncrementalHarvesterKeepsWatermarkWithoutResponseDate(t *testing.T) {
+	server := newTestRepository(10, 3, 0).serve(t)
+	watermark := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := &FileCheckpoint{Path: filepath.Join(t.TempDir(), "state.json")}
+	if err := store.Save(context.Background(), HarvestState{Watermark: watermark}); err != nil {
+		t.Fatal(err)
+	}
+	harvester := &IncrementalHarvester{Request: testRequest(server), Store: store}
+
+	// No records match, so there is no responseDate to move to
+	if _, err := harvester.Run(context.Background(), func(*Record) {}); err != nil {
+		t.Fatal(err)
+	}
+	if state, _, _ := store.Load(context.Background()); !state.Watermark.Equal(watermark) {
+		t.Errorf("watermark %v, want it kept at %v", state.Watermark, watermark)
+	}
+}
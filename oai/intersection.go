@@ -0,0 +1,115 @@
+package oai
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// A set of record identifiers; implement it on a disk store or a bloom
+// filter for harvests with more identifiers than fit in memory
+type IdentifierSet interface {
+	Add(identifier string) error
+	Contains(identifier string) (bool, error)
+}
+
+// An IdentifierSet and DatestampSet in memory, ready to use as its zero value
+type MemoryIdentifierSet struct {
+	mu          sync.Mutex
+	identifiers map[string]time.Time
+}
+
+func (set *MemoryIdentifierSet) Add(identifier string) error {
+	return set.AddDatestamp(identifier, time.Time{})
+}
+
+func (set *MemoryIdentifierSet) Contains(identifier string) (bool, error) {
+	_, ok, err := set.Datestamp(identifier)
+	return ok, err
+}
+
+func (set *MemoryIdentifierSet) AddDatestamp(identifier string, datestamp time.Time) error {
+	set.mu.Lock()
+	defer set.mu.Unlock()
+	if set.identifiers == nil {
+		set.identifiers = map[string]time.Time{}
+	}
+	set.identifiers[identifier] = datestamp
+	return nil
+}
+
+func (set *MemoryIdentifierSet) Datestamp(identifier string) (time.Time, bool, error) {
+	set.mu.Lock()
+	defer set.mu.Unlock()
+	datestamp, ok := set.identifiers[identifier]
+	return datestamp, ok, nil
+}
+
+// Harvest the records that are members of both setA and setB
+// call the record callback function for each Record
+func (req *Request) HarvestSetIntersection(setA, setB string, callback func(*Record)) {
+	if _, err := req.HarvestSetIntersectionContext(context.Background(), setA, setB, nil, callback); err != nil {
+		panic(err)
+	}
+}
+
+// Harvest the records that are members of both setA and setB, which
+// OAI-PMH cannot ask for: the identifiers of setA are listed into the
+// given IdentifierSet (a MemoryIdentifierSet if nil), then the records of
+// setB are harvested and only those listed in setA are kept; list the
+// smaller set as setA. MaxRecords and MaxBatches apply to the harvest
+// of setB
+// call the record callback function for each Record
+// and return the Summary of both harvests and the error that ended it, if any
+func (req *Request) HarvestSetIntersectionContext(ctx context.Context, setA, setB string, identifiers IdentifierSet, callback func(*Record)) (Summary, error) {
+	req = req.Clone()
+	req.Checkpoint = nil
+	if identifiers == nil {
+		identifiers = &MemoryIdentifierSet{}
+	}
+	start, summary := time.Now(), Summary{}
+
+	list := req.Clone()
+	list.Verb = VerbListIdentifiers
+	list.Set = setA
+	list.MaxRecords, list.MaxBatches = 0, 0
+	err := list.harvest(ctx, &summary, func(resp *Response) error {
+		// Deleted records are members too, so that their deletion in
+		// setB is delivered
+		for _, header := range resp.ListIdentifiers.Headers {
+			if err := identifiers.Add(header.Identifier); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	if err == nil {
+		err = req.initDedupe()
+	}
+	if err == nil {
+		records := req.Clone()
+		records.Verb = VerbListRecords
+		records.Set = setB
+		err = records.harvest(ctx, &summary, func(resp *Response) error {
+			members := []Record{}
+			for _, record := range resp.ListRecords.Records {
+				member, err := identifiers.Contains(record.Header.Identifier)
+				if err != nil {
+					return err
+				}
+				if member {
+					members = append(members, record)
+				}
+			}
+			return records.deliverRecords(&summary, members, func(record *Record) error {
+				callback(record)
+				return nil
+			})
+		})
+	}
+
+	err = summary.finish(start, err)
+	req.logSummary(summary, err)
+	return summary, err
+}
@@ -0,0 +1,55 @@
+package oai
+
+import (
+	"context"
+	"testing"
+)
+
+// A testRepository whose records with an identifier divisible by four are
+// in the set four as well, and with records oai:test:2, 5, 8 and 11 deleted
+func newIntersectionRepository() *testRepository {
+	repo := newTestRepository(12, 10, 3)
+	for i := range repo.records {
+		if i%4 == 0 {
+			repo.records[i].sets = append(repo.records[i].sets, "four")
+		}
+	}
+	return repo
+}
+
+func TestHarvestSetIntersectionDeliversDeletedMembers(t *testing.T) {
+	server := newIntersectionRepository().serve(t)
+
+	var records []*Record
+	summary, err := testRequest(server).HarvestSetIntersectionContext(context.Background(), "four", "even", nil,
+		func(record *Record) { records = append(records, record) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := identifiers(records); len(ids) != 3 || ids[0] != "oai:test:0" || ids[1] != "oai:test:4" || ids[2] != "oai:test:8" {
+		t.Errorf("delivered %v, want oai:test:0, 4 and 8", ids)
+	}
+	if summary.Records != 3 || summary.Deleted != 1 {
+		t.Errorf("summary of %d records, %d deleted, want 3 and 1", summary.Records, summary.Deleted)
+	}
+}
+
+func TestHarvestSetIntersectionAppliesFiltersAndMaxRecords(t *testing.T) {
+	server := newIntersectionRepository().serve(t)
+	req := testRequest(server)
+	req.RecordFilter = func(record *Record) bool { return record.Header.Identifier != "oai:test:0" }
+	req.MaxRecords = 1
+
+	var records []*Record
+	summary, err := req.HarvestSetIntersectionContext(context.Background(), "four", "even", nil,
+		func(record *Record) { records = append(records, record) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := identifiers(records); len(ids) != 1 || ids[0] != "oai:test:4" {
+		t.Errorf("delivered %v, want only oai:test:4", ids)
+	}
+	if summary.Rejected != 1 || !summary.Truncated {
+		t.Errorf("summary of %d rejected, truncated %v, want 1 rejected and truncated", summary.Rejected, summary.Truncated)
+	}
+}
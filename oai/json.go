@@ -0,0 +1,64 @@
+package oai
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// Encode the metadata as a JSON string holding its XML
+func (md Metadata) MarshalJSON() ([]byte, error) { return json.Marshal(string(md.Body)) }
+
+// Encode the about container as a JSON string holding its XML
+func (ab About) MarshalJSON() ([]byte, error) { return json.Marshal(string(ab.Body)) }
+
+// Encode the description as a JSON string holding its XML
+func (desc Description) MarshalJSON() ([]byte, error) { return json.Marshal(string(desc.Body)) }
+
+// Encode the Record as a JSON object with its header, and its metadata
+// and about containers as XML strings when present; the keys are always
+// written in the same order, so the output suits NDJSON exports
+func (record Record) MarshalJSON() ([]byte, error) {
+	object := struct {
+		Header   Header    `json:"header"`
+		Metadata *Metadata `json:"metadata,omitempty"`
+		About    []About   `json:"about,omitempty"`
+	}{Header: record.Header}
+
+	if len(record.Metadata.Body) > 0 {
+		object.Metadata = &record.Metadata
+	}
+	for _, about := range record.About {
+		if len(about.Body) > 0 {
+			object.About = append(object.About, about)
+		}
+	}
+	return json.Marshal(object)
+}
+
+// Harvest the records of a complete OAI set
+// write each Record to w as a line of JSON (NDJSON)
+func (req *Request) HarvestJSON(w io.Writer) error {
+	_, err := req.HarvestJSONContext(context.Background(), w)
+	return err
+}
+
+// Harvest the records of a complete OAI set
+// write each Record to w as a line of JSON (NDJSON), an object with its
+// header and its metadata and about containers as XML strings
+// and return the Summary of the harvest and the error that ended it, if any,
+// including a failure to write, after which the Summary counts the records
+// written before
+func (req *Request) HarvestJSONContext(ctx context.Context, w io.Writer) (Summary, error) {
+	req = req.Clone()
+	req.Verb = VerbListRecords
+	start, summary := time.Now(), Summary{}
+	encoder := json.NewEncoder(w)
+	err := req.harvestRecords(ctx, &summary, func(record *Record) error {
+		return encoder.Encode(record)
+	})
+	err = summary.finish(start, err)
+	req.logSummary(summary, err)
+	return summary, err
+}
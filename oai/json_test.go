@@ -0,0 +1,49 @@
+package oai
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+var errWrite = errors.New("write failed")
+
+// An io.Writer that always fails
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errWrite
+}
+
+func TestHarvestJSONReturnsWriteError(t *testing.T) {
+	for _, pageSize := range []int{3, 10} {
+		server := newTestRepository(5, pageSize, 0).serve(t)
+		_, err := testRequest(server).HarvestJSONContext(context.Background(), failingWriter{})
+		if !errors.Is(err, errWrite) {
+			t.Errorf("pages of %d: got %v, want the write error", pageSize, err)
+		}
+	}
+}
+
+// An io.Writer that fails after the given number of writes
+type failingAfter struct{ writes int }
+
+func (w *failingAfter) Write(p []byte) (int, error) {
+	if w.writes == 0 {
+		return 0, errWrite
+	}
+	w.writes--
+	return len(p), nil
+}
+
+func TestHarvestJSONCountsTheRecordsWritten(t *testing.T) {
+	server := newTestRepository(5, 10, 0).serve(t)
+	summary, err := testRequest(server).HarvestJSONContext(context.Background(), &failingAfter{writes: 2})
+	var harvestError *HarvestError
+	if !errors.As(err, &harvestError) || !errors.Is(err, errWrite) {
+		t.Fatalf("got %v, want a HarvestError of the write error", err)
+	}
+	if summary.Records != 2 || summary.Outcome != OutcomeAborted || harvestError.Summary.Records != 2 {
+		t.Errorf("summary of %d records, outcome %v, want the 2 written and aborted", summary.Records, summary.Outcome)
+	}
+}
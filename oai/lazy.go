@@ -0,0 +1,108 @@
+package oai
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// A listed record whose metadata is fetched with GetRecord only when asked
+// for, through the client and rate limiters of the harvest that listed it
+type LazyRecord struct {
+	Header Header
+
+	req     *Request
+	fetches *lazyFetches
+
+	mu     sync.Mutex
+	record *Record
+}
+
+// The GetRecord requests of the LazyRecords of a harvest, counted in its
+// Summary as long as the harvest runs
+type lazyFetches struct {
+	mu      sync.Mutex
+	summary Summary
+}
+
+// The metadata of the record, fetched with GetRecord the first time
+func (lazy *LazyRecord) Metadata() (Metadata, error) {
+	return lazy.MetadataContext(context.Background())
+}
+
+// The metadata of the record, fetched with GetRecord the first time;
+// a deleted record has none, and costs no request
+func (lazy *LazyRecord) MetadataContext(ctx context.Context) (Metadata, error) {
+	record, err := lazy.Record(ctx)
+	if err != nil {
+		return Metadata{}, err
+	}
+	return record.Metadata, nil
+}
+
+// The complete record, fetched with GetRecord the first time; a failed
+// fetch is tried again on the next call. Safe for concurrent use
+func (lazy *LazyRecord) Record(ctx context.Context) (*Record, error) {
+	lazy.mu.Lock()
+	defer lazy.mu.Unlock()
+	if lazy.record != nil {
+		return lazy.record, nil
+	}
+
+	var summary Summary
+	record, err := lazy.req.getRecord(ctx, &summary, lazy.Header)
+	lazy.fetches.mu.Lock()
+	lazy.fetches.summary.merge(summary)
+	lazy.fetches.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	lazy.record = record
+	return record, nil
+}
+
+// Harvest the headers of a complete OAI set
+// call the callback function for each LazyRecord
+func (req *Request) HarvestLazy(callback func(*LazyRecord)) {
+	if _, err := req.HarvestLazyContext(context.Background(), callback); err != nil {
+		panic(err)
+	}
+}
+
+// Harvest the headers of a complete OAI set with ListIdentifiers, to walk
+// a large set cheaply and fetch the metadata of the records of interest only
+// call the callback function for each LazyRecord
+// and return the Summary of the harvest and the error that ended it, if any
+//
+// The metadata is fetched in the MetadataPrefix of the Request; the
+// GetRecord requests made before the harvest returns count in its Summary.
+// Deleted records are kept from the callback by SkipDeleted and OnDeleted
+// as in a ListRecords harvest
+func (req *Request) HarvestLazyContext(ctx context.Context, callback func(*LazyRecord)) (Summary, error) {
+	req = req.Clone()
+	req.Verb = VerbListIdentifiers
+
+	fetches := &lazyFetches{}
+	start, summary := time.Now(), Summary{}
+	err := req.harvest(ctx, &summary, func(resp *Response) error {
+		for _, header := range resp.ListIdentifiers.Headers {
+			if req.recordLimitReached(&summary) {
+				break
+			}
+			if !req.setsAllow(&header) {
+				summary.Filtered++
+				continue
+			}
+			lazy := &LazyRecord{Header: header, req: req, fetches: fetches}
+			req.deliver(&summary, &Record{Header: header}, func(*Record) { callback(lazy) })
+		}
+		return nil
+	})
+
+	fetches.mu.Lock()
+	summary.merge(fetches.summary)
+	fetches.mu.Unlock()
+	err = summary.finish(start, err)
+	req.logSummary(summary, err)
+	return summary, err
+}
@@ -0,0 +1,53 @@
+package oai
+
+import (
+	"log/slog"
+	"time"
+)
+
+// Discards all log records, the default when no Logger is configured
+var discardLogger = slog.New(slog.DiscardHandler)
+
+// The Logger of the Request, or a logger discarding everything
+func (req *Request) logger() *slog.Logger {
+	if req.Logger == nil {
+		return discardLogger
+	}
+	return req.Logger
+}
+
+// Shorten a resumption token for logging, as some repositories pack
+// the complete state of the list into it
+func truncateToken(token string) string {
+	const maxLength = 32
+	if len(token) <= maxLength {
+		return token
+	}
+	return token[:maxLength] + "..."
+}
+
+// Log the end of a harvest with its Summary
+func (req *Request) logSummary(summary Summary, err error) {
+	attrs := []any{
+		slog.String("verb", string(req.Verb)),
+		slog.String("baseURL", req.BaseUrl),
+		slog.Int("requests", summary.Requests),
+		slog.Int("retries", summary.Retries),
+		slog.Int64("bytes", summary.Bytes),
+		slog.Int64("uncompressedBytes", summary.UncompressedBytes),
+		slog.Int("records", summary.Records),
+		slog.Int("deleted", summary.Deleted),
+		slog.Int("duplicates", summary.Duplicates),
+		slog.Int("filtered", summary.Filtered),
+		slog.Int("rejected", summary.Rejected),
+		slog.Int("stale", summary.Stale),
+		slog.Duration("slept", summary.Slept.Round(time.Millisecond)),
+		slog.Bool("truncated", summary.Truncated),
+		slog.Duration("duration", summary.Duration.Round(time.Millisecond)),
+	}
+	if err != nil {
+		req.logger().Error("oai harvest aborted", append(attrs, slog.Any("error", err))...)
+		return
+	}
+	req.logger().Info("oai harvest completed", attrs...)
+}
@@ -0,0 +1,12 @@
+package oai
+
+import "github.com/horstmumpitz/goharvest/oai/marcxml"
+
+// Decode the metadata of the Record as MARC 21 XML
+func (record *Record) MARCXML() (*marcxml.Record, error) {
+	marc := &marcxml.Record{}
+	if err := record.DecodeMetadataInto(marc); err != nil {
+		return nil, err
+	}
+	return marc, nil
+}
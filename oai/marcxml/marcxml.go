@@ -0,0 +1,143 @@
+// Package marcxml provides the MARC 21 XML (marcxml / marc21) metadata
+// format, common in library repositories
+package marcxml
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+)
+
+// The namespace of MARC 21 XML
+const Namespace = "http://www.loc.gov/MARC21/slim"
+
+// The leader of a MARC record: 24 positions of coded information
+type Leader string
+
+// The character at the given position of the leader, 0 if it is too short
+func (leader Leader) At(position int) byte {
+	if position < 0 || position >= len(leader) {
+		return 0
+	}
+	return leader[position]
+}
+
+// The record status (position 05), e.g. 'n' for new or 'd' for deleted
+func (leader Leader) RecordStatus() byte { return leader.At(5) }
+
+// The type of record (position 06), e.g. 'a' for language material
+func (leader Leader) TypeOfRecord() byte { return leader.At(6) }
+
+// The bibliographic level (position 07), e.g. 'm' for monograph
+func (leader Leader) BibliographicLevel() byte { return leader.At(7) }
+
+// A control field (tags 001-009), holding a single value
+type ControlField struct {
+	Tag   string `xml:"tag,attr"`
+	Value string `xml:",chardata"`
+}
+
+// A subfield of a data field, identified by its code
+type SubField struct {
+	Code  string `xml:"code,attr"`
+	Value string `xml:",chardata"`
+}
+
+// A data field with its two indicators and its subfields
+type DataField struct {
+	Tag       string     `xml:"tag,attr"`
+	Ind1      string     `xml:"ind1,attr"`
+	Ind2      string     `xml:"ind2,attr"`
+	SubFields []SubField `xml:"subfield"`
+}
+
+// A MARC record
+type Record struct {
+	Type          string         `xml:"type,attr,omitempty"`
+	Leader        Leader         `xml:"leader"`
+	ControlFields []ControlField `xml:"controlfield"`
+	DataFields    []DataField    `xml:"datafield"`
+}
+
+// The value of the first control field with the given tag
+func (record *Record) GetControlField(tag string) (string, bool) {
+	for _, field := range record.ControlFields {
+		if field.Tag == tag {
+			return field.Value, true
+		}
+	}
+	return "", false
+}
+
+// All data fields with the given tag, in record order
+func (record *Record) GetFields(tag string) []DataField {
+	fields := []DataField{}
+	for _, field := range record.DataFields {
+		if field.Tag == tag {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// The value of the first subfield with the given code
+func (field *DataField) GetSubfield(code byte) (string, bool) {
+	for _, subField := range field.SubFields {
+		if subField.Code == string(code) {
+			return subField.Value, true
+		}
+	}
+	return "", false
+}
+
+// The values of all subfields with the given code, in field order
+func (field *DataField) GetSubfields(code byte) []string {
+	values := []string{}
+	for _, subField := range field.SubFields {
+		if subField.Code == string(code) {
+			values = append(values, subField.Value)
+		}
+	}
+	return values
+}
+
+// Decode a record element, or the first record of a collection element
+func (record *Record) UnmarshalXML(decoder *xml.Decoder, start xml.StartElement) error {
+	type plain Record
+	if start.Name.Local != "collection" {
+		return decoder.DecodeElement((*plain)(record), &start)
+	}
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return errors.New("marcxml: no record element")
+		}
+		if err != nil {
+			return err
+		}
+		switch element := token.(type) {
+		case xml.StartElement:
+			if element.Name.Local == "record" {
+				if err := decoder.DecodeElement((*plain)(record), &element); err != nil {
+					return err
+				}
+				return decoder.Skip()
+			}
+			if err := decoder.Skip(); err != nil {
+				return err
+			}
+		case xml.EndElement:
+			return errors.New("marcxml: no record element")
+		}
+	}
+}
+
+// Decode a MARC record, or the first record of a collection
+func Decode(data []byte) (*Record, error) {
+	record := &Record{}
+	if err := xml.Unmarshal(data, record); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
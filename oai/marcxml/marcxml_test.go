@@ -0,0 +1,73 @@
+package marcxml
+
+import (
+	"io/ioutil"
+	"reflect"
+	"testing"
+)
+
+func decodeFixture(t *testing.T) *Record {
+	t.Helper()
+	data, err := ioutil.ReadFile("testdata/record.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	record, err := Decode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return record
+}
+
+func TestDecodeFirstRecordOfCollection(t *testing.T) {
+	record := decodeFixture(t)
+	if record.Type != "Bibliographic" || len(record.DataFields) != 7 {
+		t.Fatalf("type %q with %d data fields, want the first record with 7", record.Type, len(record.DataFields))
+	}
+	if id, ok := record.GetControlField("001"); !ok || id != "92005291" {
+		t.Errorf("control field 001 %q", id)
+	}
+	if _, ok := record.GetControlField("005"); ok {
+		t.Error("found a missing control field")
+	}
+	leader := record.Leader
+	if leader.RecordStatus() != 'c' || leader.TypeOfRecord() != 'a' || leader.BibliographicLevel() != 'm' {
+		t.Errorf("leader %q read as status %c, type %c, level %c", leader,
+			leader.RecordStatus(), leader.TypeOfRecord(), leader.BibliographicLevel())
+	}
+	if Leader("short").At(10) != 0 {
+		t.Error("a position past the leader is not 0")
+	}
+}
+
+func TestRepeatedFieldsAndSubfields(t *testing.T) {
+	record := decodeFixture(t)
+
+	subjects := record.GetFields("650")
+	if len(subjects) != 3 {
+		t.Fatalf("%d 650 fields, want 3", len(subjects))
+	}
+	indicators := []string{}
+	for _, field := range subjects {
+		indicators = append(indicators, field.Ind1+"/"+field.Ind2)
+	}
+	if want := []string{" /0", " /0", " /1"}; !reflect.DeepEqual(indicators, want) {
+		t.Errorf("indicators %q, want %q", indicators, want)
+	}
+
+	if value, ok := subjects[2].GetSubfield('x'); !ok || value != "Poetry." {
+		t.Errorf("first subfield x %q, want Poetry.", value)
+	}
+	if values := subjects[2].GetSubfields('x'); !reflect.DeepEqual(values, []string{"Poetry.", "Juvenile literature."}) {
+		t.Errorf("subfields x %q", values)
+	}
+	if values := subjects[1].GetSubfields('x'); len(values) != 0 {
+		t.Errorf("subfields x %q of a field without any", values)
+	}
+	if _, ok := subjects[1].GetSubfield('x'); ok {
+		t.Error("found a missing subfield")
+	}
+	if fields := record.GetFields("999"); len(fields) != 0 {
+		t.Errorf("%d fields for a missing tag", len(fields))
+	}
+}
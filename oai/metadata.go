@@ -0,0 +1,96 @@
+package oai
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Returned when decoding the metadata of a record that has none,
+// such as a deleted record
+var ErrNoMetadata = errors.New("oai: record has no metadata")
+
+// The local name and namespace of the root element of the metadata,
+// which identify its format; only the start of the body is scanned
+func (md Metadata) RootElement() (name, namespace string, err error) {
+	_, root, err := md.root()
+	if err != nil {
+		return "", "", err
+	}
+	return root.Name.Local, root.Name.Space, nil
+}
+
+// Decode the metadata of the Record into v, an XML-annotated struct
+// pointer of the caller's choosing
+func (record *Record) DecodeMetadataInto(v interface{}) error {
+	if len(bytes.TrimSpace(record.Metadata.Body)) == 0 {
+		return fmt.Errorf("oai: decoding metadata of %s: %w", record.Header.Identifier, ErrNoMetadata)
+	}
+	if err := record.Metadata.decode(v); err != nil {
+		return fmt.Errorf("oai: decoding metadata of %s: %w", record.Header.Identifier, err)
+	}
+	return nil
+}
+
+// Decode the metadata of the Record into a new value of type T
+func DecodeMetadata[T any](record *Record) (T, error) {
+	var metadata T
+	err := record.DecodeMetadataInto(&metadata)
+	return metadata, err
+}
+
+// Decode the metadata into v, an XML-annotated struct pointer for MODS,
+// MARCXML or any other schema, like xml.Unmarshal of the Body but with the
+// namespace prefixes the repository declared outside the metadata in scope
+func (md Metadata) Unmarshal(v interface{}) error {
+	if len(bytes.TrimSpace(md.Body)) == 0 {
+		return fmt.Errorf("oai: unmarshaling metadata: %w", ErrNoMetadata)
+	}
+	if err := md.decode(v); err != nil {
+		return fmt.Errorf("oai: unmarshaling metadata into %T: %w", v, err)
+	}
+	return nil
+}
+
+// Decode the metadata body into v
+func (md Metadata) decode(v interface{}) error {
+	decoder, root, err := md.root()
+	if err != nil {
+		return err
+	}
+	return decoder.DecodeElement(v, &root)
+}
+
+// Start decoding the metadata body up to its root element, with the
+// namespace prefixes declared outside the metadata in scope
+func (md Metadata) root() (*xml.Decoder, xml.StartElement, error) {
+	var wrapped bytes.Buffer
+	wrapped.WriteString("<metadata")
+	for _, attr := range md.namespaces {
+		wrapped.WriteString(" xmlns:" + attr.Name.Local + `="`)
+		xml.EscapeText(&wrapped, []byte(attr.Value))
+		wrapped.WriteString(`"`)
+	}
+	wrapped.WriteString(">")
+	wrapped.Write(md.Body)
+	wrapped.WriteString("</metadata>")
+
+	decoder := xml.NewDecoder(&wrapped)
+	if _, err := decoder.Token(); err != nil {
+		return nil, xml.StartElement{}, err
+	}
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, xml.StartElement{}, err
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			return decoder, start, nil
+		}
+		if _, ok := token.(xml.EndElement); ok {
+			return nil, xml.StartElement{}, io.ErrUnexpectedEOF
+		}
+	}
+}
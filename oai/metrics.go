@@ -0,0 +1,41 @@
+package oai
+
+import "time"
+
+// Receives measurements of the requests and pages of a harvest, so they
+// can be exported, for instance by implementing it with Prometheus
+// collectors
+type Metrics interface {
+	// An HTTP request was answered, or failed (status 0), after dur
+	ObserveRequest(verb string, dur time.Duration, status int)
+	// A failed request is about to be retried
+	ObserveRetry(verb string)
+	// The bytes of a response body were downloaded
+	ObserveBytes(verb string, bytes int)
+	// A page holding this number of records or headers was harvested
+	ObserveBatch(records int)
+}
+
+func (req *Request) observeRequest(dur time.Duration, status int) {
+	if req.Metrics != nil {
+		req.Metrics.ObserveRequest(string(req.Verb), dur, status)
+	}
+}
+
+func (req *Request) observeRetry() {
+	if req.Metrics != nil {
+		req.Metrics.ObserveRetry(string(req.Verb))
+	}
+}
+
+func (req *Request) observeBytes(bytes int) {
+	if req.Metrics != nil {
+		req.Metrics.ObserveBytes(string(req.Verb), bytes)
+	}
+}
+
+func (req *Request) observeBatch(records int) {
+	if req.Metrics != nil {
+		req.Metrics.ObserveBatch(records)
+	}
+}
@@ -0,0 +1,12 @@
+package oai
+
+import "github.com/horstmumpitz/goharvest/oai/mods"
+
+// Decode the metadata of the Record as MODS
+func (record *Record) MODS() (*mods.MODS, error) {
+	modsRecord := &mods.MODS{}
+	if err := record.DecodeMetadataInto(modsRecord); err != nil {
+		return nil, err
+	}
+	return modsRecord, nil
+}
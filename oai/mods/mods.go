@@ -0,0 +1,345 @@
+// Package mods provides the commonly used elements of the Metadata Object
+// Description Schema (MODS) 3.x metadata format
+//
+// Attributes and elements without a field of their own are kept in the
+// OtherAttrs and Other fields of the element they occur in, so encoding a
+// decoded record again loses nothing, although the unknown elements move
+// behind the known ones. Namespace declarations are not kept as
+// attributes: the encoder declares the namespaces it needs, but for those
+// of the root and of unknown elements, which are kept for their content
+package mods
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+)
+
+const (
+	// The metadataPrefix most repositories use for the format
+	MetadataPrefix = "mods"
+	// The namespace of MODS 3.x
+	Namespace = "http://www.loc.gov/mods/v3"
+)
+
+// The namespace of the xml prefix, as in xml:lang
+const xmlNamespace = "http://www.w3.org/XML/1998/namespace"
+
+// A MODS record
+type MODS struct {
+	XMLName    xml.Name     `xml:"mods"`
+	Version    string       `xml:"version,attr,omitempty"`
+	TitleInfo  []TitleInfo  `xml:"titleInfo"`
+	Name       []Name       `xml:"name"`
+	OriginInfo []OriginInfo `xml:"originInfo"`
+	Identifier []Identifier `xml:"identifier"`
+	Subject    []Subject    `xml:"subject"`
+	Location   []Location   `xml:"location"`
+	RecordInfo []RecordInfo `xml:"recordInfo"`
+
+	// The attributes and elements not covered by the fields above
+	OtherAttrs Attrs        `xml:",any,attr"`
+	Other      []RawElement `xml:",any"`
+
+	// The namespace prefixes declared on the mods element, which the
+	// unknown elements may use
+	namespaces []xml.Attr
+}
+
+// The attributes of an element without a field of their own, such as
+// displayLabel, xml:lang or xlink:href; namespace declarations are left out
+type Attrs []xml.Attr
+
+func (attrs *Attrs) UnmarshalXMLAttr(attr xml.Attr) error {
+	if !isDeclaration(attr) {
+		*attrs = append(*attrs, attr)
+	}
+	return nil
+}
+
+// An element kept as raw XML, with the namespace prefixes declared on it
+// that its content uses
+type RawElement struct {
+	XMLName xml.Name
+	Attrs   []xml.Attr `xml:",any,attr"`
+	Inner   []byte     `xml:",innerxml"`
+}
+
+// Decode an element as raw XML; the declaration of the default namespace
+// is left out, the namespace being that of XMLName, as are the prefixes
+// the content does not use
+func (raw *RawElement) UnmarshalXML(decoder *xml.Decoder, start xml.StartElement) error {
+	var inner struct {
+		Inner []byte `xml:",innerxml"`
+	}
+	if err := decoder.DecodeElement(&inner, &start); err != nil {
+		return err
+	}
+	raw.XMLName, raw.Attrs, raw.Inner = start.Name, nil, inner.Inner
+	for _, attr := range start.Attr {
+		switch {
+		case attr.Name.Space == "" && attr.Name.Local == "xmlns":
+		case attr.Name.Space == "xmlns" && !bytes.Contains(raw.Inner, []byte(attr.Name.Local+":")):
+		default:
+			raw.Attrs = append(raw.Attrs, attr)
+		}
+	}
+	return nil
+}
+
+// Encode the element with its attributes and namespace declarations as
+// they were, and its content verbatim; an element in the MODS namespace
+// takes it from the mods element
+func (raw RawElement) MarshalXML(encoder *xml.Encoder, start xml.StartElement) error {
+	name := raw.XMLName
+	if name.Space == Namespace {
+		name.Space = ""
+	}
+	return encoder.EncodeElement(struct {
+		Inner []byte `xml:",innerxml"`
+	}{raw.Inner}, verbatimStart(name, raw.Attrs, raw.Inner))
+}
+
+// A text, such as a title or a publisher
+type Text struct {
+	Value string `xml:",chardata"`
+
+	OtherAttrs Attrs        `xml:",any,attr"`
+	Other      []RawElement `xml:",any"`
+}
+
+// The text, empty for a missing element
+func (text *Text) String() string {
+	if text == nil {
+		return ""
+	}
+	return text.Value
+}
+
+// A text with an optional type or authority attribute, such as an
+// identifier, a namePart, a roleTerm or a topic
+type Term struct {
+	Type      string `xml:"type,attr,omitempty"`
+	Authority string `xml:"authority,attr,omitempty"`
+	Value     string `xml:",chardata"`
+
+	OtherAttrs Attrs        `xml:",any,attr"`
+	Other      []RawElement `xml:",any"`
+}
+
+// An identifier, such as an ISBN, URI or local identifier
+type Identifier struct {
+	Type   string `xml:"type,attr,omitempty"`
+	Source string `xml:"source,attr,omitempty"`
+	Value  string `xml:",chardata"`
+
+	OtherAttrs Attrs        `xml:",any,attr"`
+	Other      []RawElement `xml:",any"`
+}
+
+// A date, with its encoding and whether it is the key date
+type Date struct {
+	Encoding string `xml:"encoding,attr,omitempty"`
+	Point    string `xml:"point,attr,omitempty"`
+	KeyDate  string `xml:"keyDate,attr,omitempty"`
+	Value    string `xml:",chardata"`
+
+	OtherAttrs Attrs        `xml:",any,attr"`
+	Other      []RawElement `xml:",any"`
+}
+
+type TitleInfo struct {
+	Type       string `xml:"type,attr,omitempty"`
+	Lang       string `xml:"lang,attr,omitempty"`
+	NonSort    *Text  `xml:"nonSort"`
+	Title      *Text  `xml:"title"`
+	SubTitle   *Text  `xml:"subTitle"`
+	PartNumber *Text  `xml:"partNumber"`
+	PartName   *Text  `xml:"partName"`
+
+	OtherAttrs Attrs        `xml:",any,attr"`
+	Other      []RawElement `xml:",any"`
+}
+
+// Decode a titleInfo element, keeping xml:lang apart from lang, which
+// the Lang field would otherwise take both of
+func (titleInfo *TitleInfo) UnmarshalXML(decoder *xml.Decoder, start xml.StartElement) error {
+	type plain TitleInfo
+	attrs, xmlLang := []xml.Attr{}, []xml.Attr{}
+	for _, attr := range start.Attr {
+		if attr.Name.Space == xmlNamespace {
+			xmlLang = append(xmlLang, attr)
+		} else {
+			attrs = append(attrs, attr)
+		}
+	}
+	start.Attr = attrs
+	if err := decoder.DecodeElement((*plain)(titleInfo), &start); err != nil {
+		return err
+	}
+	titleInfo.OtherAttrs = append(titleInfo.OtherAttrs, xmlLang...)
+	return nil
+}
+
+type Role struct {
+	RoleTerm []Term `xml:"roleTerm"`
+
+	OtherAttrs Attrs        `xml:",any,attr"`
+	Other      []RawElement `xml:",any"`
+}
+
+type Name struct {
+	Type        string `xml:"type,attr,omitempty"`
+	Authority   string `xml:"authority,attr,omitempty"`
+	NamePart    []Term `xml:"namePart"`
+	DisplayForm *Text  `xml:"displayForm"`
+	Role        []Role `xml:"role"`
+
+	OtherAttrs Attrs        `xml:",any,attr"`
+	Other      []RawElement `xml:",any"`
+}
+
+type Place struct {
+	PlaceTerm []Term `xml:"placeTerm"`
+
+	OtherAttrs Attrs        `xml:",any,attr"`
+	Other      []RawElement `xml:",any"`
+}
+
+type OriginInfo struct {
+	Place         []Place `xml:"place"`
+	Publisher     []Text  `xml:"publisher"`
+	DateIssued    []Date  `xml:"dateIssued"`
+	DateCreated   []Date  `xml:"dateCreated"`
+	DateCaptured  []Date  `xml:"dateCaptured"`
+	DateModified  []Date  `xml:"dateModified"`
+	CopyrightDate []Date  `xml:"copyrightDate"`
+	Edition       *Text   `xml:"edition"`
+	Issuance      *Text   `xml:"issuance"`
+
+	OtherAttrs Attrs        `xml:",any,attr"`
+	Other      []RawElement `xml:",any"`
+}
+
+type Subject struct {
+	Authority  string `xml:"authority,attr,omitempty"`
+	Topic      []Term `xml:"topic"`
+	Geographic []Term `xml:"geographic"`
+	Temporal   []Term `xml:"temporal"`
+	Name       []Name `xml:"name"`
+
+	OtherAttrs Attrs        `xml:",any,attr"`
+	Other      []RawElement `xml:",any"`
+}
+
+type URL struct {
+	Usage  string `xml:"usage,attr,omitempty"`
+	Access string `xml:"access,attr,omitempty"`
+	Value  string `xml:",chardata"`
+
+	OtherAttrs Attrs        `xml:",any,attr"`
+	Other      []RawElement `xml:",any"`
+}
+
+type Location struct {
+	PhysicalLocation []Term `xml:"physicalLocation"`
+	URL              []URL  `xml:"url"`
+
+	OtherAttrs Attrs        `xml:",any,attr"`
+	Other      []RawElement `xml:",any"`
+}
+
+type RecordInfo struct {
+	RecordContentSource []Term       `xml:"recordContentSource"`
+	RecordCreationDate  []Date       `xml:"recordCreationDate"`
+	RecordChangeDate    []Date       `xml:"recordChangeDate"`
+	RecordIdentifier    []Identifier `xml:"recordIdentifier"`
+	RecordOrigin        []Text       `xml:"recordOrigin"`
+
+	OtherAttrs Attrs        `xml:",any,attr"`
+	Other      []RawElement `xml:",any"`
+}
+
+// Decode a mods element
+func Decode(data []byte) (*MODS, error) {
+	record := &MODS{}
+	if err := xml.Unmarshal(data, record); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// Decode a mods element, keeping the namespace prefixes it declares
+func (record *MODS) UnmarshalXML(decoder *xml.Decoder, start xml.StartElement) error {
+	type plain MODS
+	if err := decoder.DecodeElement((*plain)(record), &start); err != nil {
+		return err
+	}
+	record.namespaces = nil
+	for _, attr := range start.Attr {
+		if isDeclaration(attr) && attr.Name.Space == "xmlns" {
+			record.namespaces = append(record.namespaces, attr)
+		}
+	}
+	return nil
+}
+
+// Encode the record as a mods element in the MODS namespace
+func (record *MODS) Marshal() ([]byte, error) {
+	type plain MODS
+	encoded := plain(*record)
+	encoded.OtherAttrs = nil
+	attrs := append(append([]xml.Attr{}, record.namespaces...), record.OtherAttrs...)
+
+	var buffer bytes.Buffer
+	encoder := xml.NewEncoder(&buffer)
+	if err := encoder.EncodeElement(&encoded, verbatimStart(xml.Name{Space: Namespace, Local: "mods"}, attrs, nil)); err != nil {
+		return nil, err
+	}
+	if err := encoder.Flush(); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// Whether the attribute declares a namespace
+func isDeclaration(attr xml.Attr) bool {
+	return attr.Name.Space == "xmlns" || (attr.Name.Space == "" && attr.Name.Local == "xmlns")
+}
+
+// A start element written as given: the namespace of the element is
+// declared as the default one, and the namespaced attributes are written
+// with the prefixes declared among the attributes, the xml prefix, or
+// prefixes of their own declared along, which the content does not use
+func verbatimStart(name xml.Name, attrs []xml.Attr, content []byte) xml.StartElement {
+	start := xml.StartElement{Name: xml.Name{Local: name.Local}}
+	if name.Space != "" {
+		start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "xmlns"}, Value: name.Space})
+	}
+
+	prefixes, taken := map[string]string{xmlNamespace: "xml"}, map[string]bool{}
+	for _, attr := range attrs {
+		if attr.Name.Space == "xmlns" {
+			prefixes[attr.Value], taken[attr.Name.Local] = attr.Name.Local, true
+			start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "xmlns:" + attr.Name.Local}, Value: attr.Value})
+		}
+	}
+	for _, attr := range attrs {
+		switch {
+		case isDeclaration(attr):
+		case attr.Name.Space == "":
+			start.Attr = append(start.Attr, attr)
+		default:
+			prefix, ok := prefixes[attr.Name.Space]
+			if !ok {
+				for n := 1; prefix == "" || taken[prefix] || bytes.Contains(content, []byte(prefix+":")); n++ {
+					prefix = fmt.Sprintf("ns%d", n)
+				}
+				prefixes[attr.Name.Space], taken[prefix] = prefix, true
+				start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "xmlns:" + prefix}, Value: attr.Name.Space})
+			}
+			start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: prefix + ":" + attr.Name.Local}, Value: attr.Value})
+		}
+	}
+	return start
+}
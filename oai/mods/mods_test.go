@@ -0,0 +1,80 @@
+package mods
+
+import (
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRoundTrip(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/record.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	record, err := Decode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded, err := record.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := Decode(encoded)
+	if err != nil {
+		t.Fatalf("decoding the encoded record: %v\n%s", err, encoded)
+	}
+	if !reflect.DeepEqual(record, again) {
+		t.Errorf("the record changed in the round trip:\n%#v\n%#v\n%s", record, again, encoded)
+	}
+
+	for _, kept := range []string{
+		`<affiliation>Leiden University</affiliation>`,
+		`<genre authority="aat">corpora</genre>`,
+		`<country>Netherlands</country>`,
+		`<frequency authority="marcfrequency">Annual</frequency>`,
+		`displayLabel="Published"`,
+		`displayLabel="DOI"`,
+		`valueURI="http://id.loc.gov/authorities/subjects/sh1"`,
+		`supplied="yes"`,
+		`note="landing page"`,
+		`<local:note local:kind="test">kept as is</local:note>`,
+		`An <i>annotated</i> corpus.`,
+	} {
+		if !strings.Contains(string(encoded), kept) {
+			t.Errorf("encoded record lost %s:\n%s", kept, encoded)
+		}
+	}
+}
+
+func TestDecode(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/record.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	record, err := Decode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	titleInfo := record.TitleInfo[0]
+	if titleInfo.Title.String() != "Annotated corpus" || titleInfo.NonSort.String() != "The " || titleInfo.PartName.String() != "" {
+		t.Errorf("title %q, nonSort %q, partName %q", titleInfo.Title, titleInfo.NonSort, titleInfo.PartName)
+	}
+	if titleInfo.Lang != "eng" {
+		t.Errorf("titleInfo lang %q, want eng, apart from xml:lang", titleInfo.Lang)
+	}
+	if attr := titleInfo.OtherAttrs[len(titleInfo.OtherAttrs)-1]; attr.Name.Space != xmlNamespace || attr.Value != "en" {
+		t.Errorf("titleInfo xml:lang %v", attr)
+	}
+	if name := record.Name[0]; name.DisplayForm.String() != "Jansen, Piet" || len(name.Other) != 2 ||
+		name.Other[0].XMLName.Local != "affiliation" || name.OtherAttrs[0].Name.Local != "href" {
+		t.Errorf("name %#v", name)
+	}
+	if subject := record.Subject[0]; subject.Topic[0].Authority != "lcsh" || len(subject.Other) != 2 {
+		t.Errorf("subject %#v", subject)
+	}
+	if len(record.Other) != 3 || record.Other[1].XMLName.Local != "extension" {
+		t.Errorf("other elements %#v", record.Other)
+	}
+}
@@ -0,0 +1,145 @@
+package oai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A repository to harvest with a MultiHarvester
+type Endpoint struct {
+	BaseUrl, Set, MetadataPrefix string
+
+	// Harvest in successive windows of this length between From and
+	// Until (the earliest datestamp of the repository and now when zero)
+	// instead of at once
+	Window      time.Duration
+	From, Until time.Time
+
+	// The callback for the records of this endpoint, instead of the
+	// combined callback of the harvest
+	Callback func(*Record)
+}
+
+// The base URL of the Endpoint, and its set if any
+func (endpoint Endpoint) source() string {
+	if endpoint.Set == "" {
+		return endpoint.BaseUrl
+	}
+	return endpoint.BaseUrl + " set " + endpoint.Set
+}
+
+// The outcome of the harvest of an Endpoint
+type EndpointReport struct {
+	Endpoint Endpoint
+	Summary  Summary
+	Err      error
+}
+
+// The outcome of the harvest of all endpoints of a MultiHarvester,
+// in the order of the endpoints
+type MultiReport []EndpointReport
+
+// The reports of the endpoints that failed
+func (report MultiReport) Failed() MultiReport {
+	failed := MultiReport{}
+	for _, endpoint := range report {
+		if endpoint.Err != nil {
+			failed = append(failed, endpoint)
+		}
+	}
+	return failed
+}
+
+// A line per endpoint with its base URL and set, and the number
+// of records harvested or the error it failed with
+func (report MultiReport) String() string {
+	lines := make([]string, len(report))
+	for i, endpoint := range report {
+		if endpoint.Err != nil {
+			lines[i] = fmt.Sprintf("%s: failed: %v", endpoint.Endpoint.source(), endpoint.Err)
+		} else {
+			lines[i] = fmt.Sprintf("%s: %d records", endpoint.Endpoint.source(), endpoint.Summary.Records)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Harvests a list of endpoints that share all settings but their base
+// URL, set, metadata prefix and window; the settings are those of
+// Request, completed by the ones of the Harvester, without its Checkpoint
+type MultiHarvester struct {
+	Harvester
+
+	// The settings for the harvest of each endpoint
+	Request Request
+}
+
+// Harvest the records of each endpoint
+// call the callback of the endpoint, or the record callback function with
+// the base URL the Record was harvested from, for each Record
+// and return the report of each endpoint and the errors of the ones that
+// failed joined together; a failing endpoint does not stop the others
+func (multi *MultiHarvester) Harvest(ctx context.Context, endpoints []Endpoint, callback func(base string, record *Record)) (MultiReport, error) {
+	harvester := multi.Harvester
+	// Share one client between all the endpoints
+	if harvester.Client == nil {
+		harvester.Client = multi.Request.Client
+	}
+
+	requests := make([]*Request, len(endpoints))
+	for i, endpoint := range endpoints {
+		req := multi.Request.Clone()
+		req.Verb = VerbListRecords
+		req.BaseUrl, req.Set = endpoint.BaseUrl, endpoint.Set
+		req.Checkpoint = nil
+		if endpoint.MetadataPrefix != "" {
+			req.MetadataPrefix = endpoint.MetadataPrefix
+		}
+		requests[i] = req
+	}
+
+	var mu sync.Mutex
+	summaries, errs := harvester.run(ctx, requests, func(ctx context.Context, i int, req *Request) (Summary, error) {
+		endpoint := endpoints[i]
+		endpointCallback := endpoint.Callback
+		if endpointCallback == nil {
+			endpointCallback = func(record *Record) {
+				mu.Lock()
+				defer mu.Unlock()
+				callback(endpoint.BaseUrl, record)
+			}
+		}
+		if endpoint.Window == 0 {
+			return req.HarvestRecordsContext(ctx, endpointCallback)
+		}
+
+		from, until := endpoint.From, endpoint.Until
+		if from.IsZero() {
+			identify, err := req.preflights.identifyRepository(ctx, req, &Summary{})
+			if err != nil {
+				return Summary{}, err
+			}
+			if from, err = identify.EarliestDatetime(); err != nil {
+				return Summary{}, err
+			}
+		}
+		if until.IsZero() {
+			until = time.Now()
+		}
+		return req.HarvestWindowedContext(ctx, from, until, endpoint.Window, endpointCallback)
+	})
+
+	report := make(MultiReport, len(endpoints))
+	failures := []error{}
+	for i, endpoint := range endpoints {
+		report[i] = EndpointReport{Endpoint: endpoint, Summary: summaries[i], Err: errs[i]}
+		if errs[i] != nil {
+			failures = append(failures, fmt.Errorf("%s: %w", endpoint.source(), errs[i]))
+		}
+	}
+	return report, errors.Join(failures...)
+}
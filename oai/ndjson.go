@@ -0,0 +1,107 @@
+package oai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+
+	"github.com/horstmumpitz/goharvest/oai/oaidc"
+)
+
+// A RecordWriter writing each record to an io.Writer as a line of JSON
+// (NDJSON); wrap the io.Writer in a gzip.Writer for compressed output
+type NDJSONWriter struct {
+	w io.Writer
+
+	// Add the Dublin Core elements of oai_dc records as a dc object
+	DublinCore bool
+}
+
+// An NDJSONWriter writing to w
+func NewNDJSONWriter(w io.Writer) *NDJSONWriter {
+	return &NDJSONWriter{w: w}
+}
+
+// A record as written by the NDJSONWriter
+type ndjsonRecord struct {
+	Identifier string              `json:"identifier"`
+	Datestamp  string              `json:"datestamp"`
+	SetSpecs   []string            `json:"setSpecs"`
+	Deleted    bool                `json:"deleted"`
+	Metadata   string              `json:"metadata,omitempty"`
+	DC         map[string][]string `json:"dc,omitempty"`
+}
+
+// Write the record as a line of JSON, the metadata as a string of XML,
+// in a single Write to the underlying io.Writer, so a line is never
+// written in part
+func (ndjsonWriter *NDJSONWriter) Write(ctx context.Context, record *Record) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	line := ndjsonRecord{
+		Identifier: record.Header.Identifier,
+		Datestamp:  record.Header.DateStamp,
+		SetSpecs:   record.Sets(),
+		Deleted:    record.Header.IsDeleted(),
+		Metadata:   string(bytes.TrimSpace(record.Metadata.Body)),
+	}
+	if line.SetSpecs == nil {
+		line.SetSpecs = []string{}
+	}
+	if ndjsonWriter.DublinCore && line.Metadata != "" {
+		if _, namespace, err := record.Metadata.RootElement(); err == nil && namespace == oaidc.Namespace {
+			if dc, err := record.DublinCore(); err == nil {
+				line.DC = dublinCoreFields(dc)
+			}
+		}
+	}
+
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	// Keep the XML readable; JSON needs no escaping of <, > and &
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(line); err != nil {
+		return err
+	}
+	_, err := ndjsonWriter.w.Write(buffer.Bytes())
+	return err
+}
+
+// Flush the underlying io.Writer if it buffers, as a gzip.Writer does,
+// without closing it
+func (ndjsonWriter *NDJSONWriter) Close() error {
+	if flusher, ok := ndjsonWriter.w.(interface{ Flush() error }); ok {
+		return flusher.Flush()
+	}
+	return nil
+}
+
+// The Dublin Core elements that occur, by their lower-case name
+func dublinCoreFields(dc *oaidc.DublinCore) map[string][]string {
+	fields := map[string][]string{}
+	for name, values := range map[string][]string{
+		"title":       dc.Title,
+		"creator":     dc.Creator,
+		"subject":     dc.Subject,
+		"description": dc.Description,
+		"publisher":   dc.Publisher,
+		"contributor": dc.Contributor,
+		"date":        dc.Date,
+		"type":        dc.Type,
+		"format":      dc.Format,
+		"identifier":  dc.Identifier,
+		"source":      dc.Source,
+		"language":    dc.Language,
+		"relation":    dc.Relation,
+		"coverage":    dc.Coverage,
+		"rights":      dc.Rights,
+	} {
+		if len(values) > 0 {
+			fields[name] = values
+		}
+	}
+	return fields
+}
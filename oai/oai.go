@@ -1,22 +1,35 @@
 package oai
 
 import (
+	"bytes"
+	"context"
 	"encoding/xml"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"log/slog"
+	"math/rand"
 	"net/http"
+	"net/url"
 	"strings"
+	"sync"
+	"time"
 )
 
 type Header struct {
-	Status     string   `xml:"status,attr"`
-	Identifier string   `xml:"identifier"`
-	DateStamp  string   `xml:"datestamp"`
-	SetSpec    []string `xml:"setSpec"`
+	Status     string   `xml:"status,attr,omitempty" json:"status,omitempty"`
+	Identifier string   `xml:"identifier" json:"identifier"`
+	DateStamp  string   `xml:"datestamp" json:"datestamp"`
+	SetSpec    []string `xml:"setSpec" json:"setSpec,omitempty"`
 }
 
 type Metadata struct {
 	Body []byte `xml:",innerxml"`
+
+	// The namespace prefixes declared outside the metadata element,
+	// which its body may use
+	namespaces []xml.Attr
 }
 
 type About struct {
@@ -26,17 +39,28 @@ type About struct {
 type Record struct {
 	Header   Header   `xml:"header"`
 	Metadata Metadata `xml:"metadata"`
-	About    About    `xml:"about"`
+	// A record may carry any number of about containers,
+	// such as provenance and rights statements
+	About []About `xml:"about"`
+}
+
+// The resumptionToken of an incomplete list, with its optional attributes;
+// the last page of a list carries an empty Token
+type ResumptionToken struct {
+	Token            string
+	CompleteListSize int
+	Cursor           int
+	ExpirationDate   time.Time
 }
 
 type ListIdentifiers struct {
-	Headers         []Header `xml:"header"`
-	ResumptionToken string   `xml:"resumptionToken"`
+	Headers         []Header        `xml:"header"`
+	ResumptionToken ResumptionToken `xml:"resumptionToken"`
 }
 
 type ListRecords struct {
-	Records         []Record `xml:"record"`
-	ResumptionToken string   `xml:"resumptionToken"`
+	Records         []Record        `xml:"record"`
+	ResumptionToken ResumptionToken `xml:"resumptionToken"`
 }
 
 type GetRecord struct {
@@ -44,9 +68,14 @@ type GetRecord struct {
 }
 
 type RequestNode struct {
-	Verb           string `xml:"verb,attr"`
-	Set            string `xml:"set,attr"`
-	MetadataPrefix string `xml:"metadataPrefix,attr"`
+	Verb            string `xml:"verb,attr,omitempty"`
+	Set             string `xml:"set,attr,omitempty"`
+	MetadataPrefix  string `xml:"metadataPrefix,attr,omitempty"`
+	Identifier      string `xml:"identifier,attr,omitempty"`
+	From            string `xml:"from,attr,omitempty"`
+	Until           string `xml:"until,attr,omitempty"`
+	ResumptionToken string `xml:"resumptionToken,attr,omitempty"`
+	BaseURL         string `xml:",chardata"`
 }
 
 type OAIError struct {
@@ -75,18 +104,20 @@ type Set struct {
 }
 
 type ListSets struct {
-	Set []Set `xml:"set"`
+	Set             []Set           `xml:"set"`
+	ResumptionToken ResumptionToken `xml:"resumptionToken"`
 }
 
 type Identify struct {
-	RepositoryName    string        `xml:"repositoryName"`
-	BaseURL           string        `xml:"baseURL"`
-	ProtocolVersion   string        `xml:"protocolVersion"`
-	AdminEmail        []string      `xml:"adminEmail"`
-	EarliestDatestamp string        `xml:"earliestDatestamp"`
-	DeletedRecord     string        `xml:"deletedRecord"`
-	Granularity       string        `xml:"granularity"`
-	Description       []Description `xml:"description"`
+	RepositoryName    string        `xml:"repositoryName" json:"repositoryName"`
+	BaseURL           string        `xml:"baseURL" json:"baseURL"`
+	ProtocolVersion   string        `xml:"protocolVersion" json:"protocolVersion"`
+	AdminEmail        []string      `xml:"adminEmail" json:"adminEmail,omitempty"`
+	EarliestDatestamp string        `xml:"earliestDatestamp" json:"earliestDatestamp"`
+	DeletedRecord     string        `xml:"deletedRecord" json:"deletedRecord"`
+	Granularity       string        `xml:"granularity" json:"granularity"`
+	Compression       []string      `xml:"compression" json:"compression,omitempty"`
+	Description       []Description `xml:"description" json:"description,omitempty"`
 }
 
 // The struct representation of an OAI-PMH XML response
@@ -101,6 +132,17 @@ type Response struct {
 	GetRecord           GetRecord           `xml:"GetRecord"`
 	ListIdentifiers     ListIdentifiers     `xml:"ListIdentifiers"`
 	ListRecords         ListRecords         `xml:"ListRecords"`
+
+	// The raw XML of the response, only retained when Request.KeepRaw is set
+	Raw []byte `xml:"-"`
+
+	// The URL the response was requested with
+	url string
+}
+
+// Whether the Header marks a deleted record
+func (header *Header) IsDeleted() bool {
+	return strings.EqualFold(strings.TrimSpace(header.Status), "deleted")
 }
 
 // Formatter for Metadata content
@@ -115,47 +157,552 @@ func (ab About) GoString() string { return fmt.Sprintf("%s", ab.Body) }
 // Perform an HTTP GET request using the OAI Requests fields
 // and return an OAI Response reference
 func (req *Request) Perform() (oaiResponse *Response) {
-	// Perform the GET request
-	resp, err := http.Get(req.String())
-	if err != nil {
+	oaiResponse, err := req.PerformContext(context.Background())
+
+	// An OAI error is part of the response, anything else is fatal
+	if _, ok := err.(*OAIError); err != nil && !ok {
 		panic(err)
 	}
 
+	return
+}
+
+// Perform an HTTP GET request using the OAI Requests fields
+// and return an OAI Response reference; the error is non-nil when the
+// request failed or when the repository replied with an OAI error, in
+// which case it is an *OAIError and the Response is returned as well
+func (req *Request) PerformContext(ctx context.Context) (oaiResponse *Response, err error) {
+	return req.perform(ctx, &Summary{})
+}
+
+// Perform the request, retrying on failure as configured,
+// and account for it in the summary
+func (req *Request) perform(ctx context.Context, summary *Summary) (*Response, error) {
+	// Refuse to send a request the repository is bound to reject
+	if !req.SkipValidation {
+		if err := req.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	requestURL, err := req.URL()
+	if err != nil {
+		return nil, err
+	}
+
+	// Replay the response from the cache if it is there,
+	// or revalidate it if there are validators
+	var cached []byte
+	if req.Cache != nil {
+		if body, ok := req.Cache.Get(requestURL); ok {
+			if req.Validators != nil {
+				cached = body
+			} else if oaiResponse, err := decodeResponse(body, req.ConfigureDecoder); err == nil {
+				req.keepRaw(oaiResponse, body)
+				oaiResponse.url = requestURL
+				if err := req.checkEcho(oaiResponse); err != nil {
+					return nil, err
+				}
+				return oaiResponse, oaiResponse.Err()
+			}
+		}
+	}
+
+	body, err := req.fetch(ctx, requestURL, 0, summary)
+	for attempt := 1; retryable(err) && attempt <= req.MaxRetries && ctx.Err() == nil; attempt++ {
+		delay := req.retryDelay(attempt, err)
+		req.logger().Warn("oai retry", slog.String("url", requestURL),
+			slog.Int("attempt", attempt), slog.Duration("delay", delay), slog.Any("reason", err))
+		if err = sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+		summary.Retries++
+		req.observeRetry()
+		body, err = req.fetch(ctx, requestURL, attempt, summary)
+	}
+	if errors.Is(err, ErrNotModified) && cached != nil {
+		body, err = cached, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Unmarshall all the data
+	oaiResponse, err := decodeResponse(body, req.ConfigureDecoder)
+	if err != nil {
+		return nil, err
+	}
+
+	req.keepRaw(oaiResponse, body)
+	oaiResponse.url = requestURL
+	if err := req.checkEcho(oaiResponse); err != nil {
+		return nil, err
+	}
+	if req.Cache != nil {
+		req.Cache.Set(requestURL, body)
+	}
+	if req.reuseBuffers() {
+		releaseBody(body)
+	}
+
+	return oaiResponse, oaiResponse.Err()
+}
+
+// Retain the raw response body on the Response if so configured
+func (req *Request) keepRaw(oaiResponse *Response, body []byte) {
+	if req.KeepRaw {
+		oaiResponse.Raw = body
+	}
+}
+
+// Perform the GET request once and return the response body
+func (req *Request) fetch(ctx context.Context, requestURL string, attempt int, summary *Summary) ([]byte, error) {
+	if req.RateLimiter != nil {
+		if err := req.RateLimiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+	}
+	if req.HostRateLimiter != nil {
+		if err := req.HostRateLimiter.Wait(ctx, req.host()); err != nil {
+			return nil, err
+		}
+	}
+
+	httpRequest, err := http.NewRequestWithContext(withAttempt(ctx, attempt), http.MethodGet, requestURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if req.AcceptGzip {
+		httpRequest.Header.Set("Accept-Encoding", "gzip")
+	}
+	req.addValidators(httpRequest, requestURL)
+	if req.BeforeRequest != nil {
+		if err := req.BeforeRequest(httpRequest); err != nil {
+			return nil, &hookError{err}
+		}
+	}
+	req.onRequest(summary, httpRequest)
+
+	req.logger().Debug("oai request", slog.String("url", requestURL), slog.Int("attempt", attempt))
+	start := time.Now()
+	summary.addRequest(req.host())
+	resp, err := req.httpClient().Do(httpRequest)
+	if err != nil {
+		req.logger().Debug("oai request failed", slog.String("url", requestURL),
+			slog.Duration("duration", time.Since(start)), slog.Any("error", err))
+		req.observeRequest(time.Since(start), 0)
+		req.onResponse(summary, httpRequest, nil, time.Since(start))
+		return nil, err
+	}
+
 	// Make sure the response body object will be closed after
 	// reading all the content body's data
 	defer resp.Body.Close()
 
 	// Read all the data
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		panic(err)
+	var body []byte
+	if req.reuseBuffers() {
+		buffer := newBuffer()
+		_, err = buffer.ReadFrom(resp.Body)
+		body = buffer.Bytes()
+	} else {
+		body, err = ioutil.ReadAll(resp.Body)
+	}
+	summary.Bytes += int64(len(body))
+	req.observeBytes(len(body))
+	req.observeRequest(time.Since(start), resp.StatusCode)
+	if err == nil && resp.Header.Get("Content-Encoding") != "" {
+		body, err = decompress(resp.Header.Get("Content-Encoding"), body)
+		resp.Header.Del("Content-Encoding")
+		resp.Uncompressed = true
+	}
+	summary.UncompressedBytes += int64(len(body))
+	switch {
+	case err != nil:
+	case resp.StatusCode == http.StatusNotModified:
+		err = ErrNotModified
+	case resp.StatusCode != http.StatusOK:
+		err = newHTTPError(requestURL, resp, body)
+	default:
+		req.storeValidators(requestURL, resp)
 	}
 
-	// Unmarshall all the data
-	err = xml.Unmarshal(body, &oaiResponse)
-	if err != nil {
-		panic(err)
+	req.logger().Debug("oai response", slog.String("url", requestURL), slog.Int("status", resp.StatusCode),
+		slog.Int("bytes", len(body)), slog.Duration("duration", time.Since(start)))
+
+	// Let the OnResponse hook read the body without consuming it
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	req.onResponse(summary, httpRequest, resp, time.Since(start))
+
+	return body, err
+}
+
+// The delay before the given retry attempt after err: the Retry-After
+// the repository asked for, or else RetryDelay (one second by default)
+// doubled for every attempt after the first
+func (req *Request) retryDelay(attempt int, err error) time.Duration {
+	var httpError *HTTPError
+	if errors.As(err, &httpError) && httpError.RetryAfter > 0 {
+		return httpError.RetryAfter
 	}
 
-	return
+	delay := req.RetryDelay
+	if delay <= 0 {
+		delay = time.Second
+	}
+	return req.RetryJitter.apply(delay << uint(attempt-1))
+}
+
+// How the delay before a retry is randomized, so harvesters that failed
+// at the same time do not retry in lockstep
+type Jitter int
+
+const (
+	// Wait exactly the backoff delay
+	JitterNone Jitter = iota
+	// Wait a random duration between zero and the backoff delay
+	JitterFull
+	// Wait half the backoff delay plus a random duration up to the other half
+	JitterEqual
+)
+
+// Randomize the delay according to the jitter
+func (jitter Jitter) apply(delay time.Duration) time.Duration {
+	switch {
+	case delay <= 1:
+		return delay
+	case jitter == JitterFull:
+		return time.Duration(rand.Int63n(int64(delay) + 1))
+	case jitter == JitterEqual:
+		return delay/2 + time.Duration(rand.Int63n(int64(delay-delay/2)+1))
+	default:
+		return delay
+	}
+}
+
+// Wait for the given duration, or until the context is done
+func sleep(ctx context.Context, duration time.Duration) error {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
 // Represents a request URL and query string to an OAI-PMH service
+//
+// Perform and the harvest methods never modify the Request they are called
+// on: pagination works on a Clone. A Request may therefore be reused, and be
+// used by several goroutines at the same time, as long as none of them
+// changes its fields while a harvest is running
 type Request struct {
-	BaseUrl, Set, MetadataPrefix, Verb, Identifier, ResumptionToken, From, Until string
+	BaseUrl, Set, MetadataPrefix, Identifier, ResumptionToken, From, Until string
+
+	// The OAI-PMH verb, one of the Verb constants
+	Verb Verb
+
+	// Limits for a harvest: stop after this many records or batches,
+	// even if the repository still returns a resumption token (0 = unlimited)
+	MaxRecords, MaxBatches int
+
+	// Which arguments are sent along with a resumption token,
+	// none besides the verb unless set to ResumptionPermissive
+	ResumptionMode ResumptionMode
+
+	// The datestamp granularity used by SetFrom and SetUntil,
+	// seconds unless set to GranularityDay
+	Granularity Granularity
+
+	// How HarvestRecords treats deleted records, which carry no metadata:
+	// when OnDeleted is set, their headers are passed to it instead of the
+	// record callback, when SkipDeleted is set they are dropped; by default
+	// they are passed to the record callback like any other record. Either
+	// way they are counted in Summary.Deleted, but only in Summary.Records
+	// when passed to the record callback
+	SkipDeleted bool
+	OnDeleted   func(*Header)
+
+	// Set by FilterSets: HarvestRecords and HarvestIdentifiers only deliver
+	// the records in one of the IncludeSets, if any, and in none of the
+	// ExcludeSets, for repositories that ignore the set argument
+	IncludeSets, ExcludeSets []string
+
+	// HarvestRecords only delivers the records for which RecordFilter,
+	// if set, returns true; a panic in it is not recovered, as with the
+	// record callback
+	RecordFilter func(*Record) bool
+
+	// For HarvestViaGetRecord: skip the records that no longer exist by
+	// the time they are fetched instead of failing, and deliver the
+	// records in the order they were listed in
+	SkipMissing     bool
+	OrderedDelivery bool
+
+	// Called after each page of a harvest to report its progress
+	OnProgress func(Progress)
+
+	// How far before the last run IncrementalHarvest starts, to catch the
+	// records on the boundary of repositories treating from as exclusive;
+	// one second if not set, none if negative
+	BoundaryOverlap time.Duration
+
+	// Called after each window of HarvestWindowed with its bounds
+	// and the Summary of its harvest
+	OnWindow func(from, until time.Time, summary Summary)
+	// The number of windows HarvestWindowed harvests at the same time,
+	// one if not set
+	WindowWorkers int
+
+	// Called after each page of a harvest has been delivered with the
+	// resumptionToken leading to the next page, empty after the last one,
+	// so it can be saved to continue the harvest later with ResumeFrom
+	OnResumptionToken func(ResumptionToken)
+
+	// Save the state of a harvest after every page, and continue from the
+	// saved state when the harvest starts unless a ResumptionToken is set;
+	// HarvestWindowed and HarvestPartitioned, which run several harvests,
+	// do not use it
+	Checkpoint CheckpointStore
+
+	// Called before every HTTP request and after every HTTP response,
+	// retries included (see IsRetry); the response is nil when the request
+	// failed and its Body may be read by the hook. A panic in a hook is
+	// recovered and reported in the Summary.HookErrors of the harvest
+	OnRequest  func(*http.Request)
+	OnResponse func(*http.Request, *http.Response, time.Duration)
+
+	// Called just before every HTTP request is sent, before OnRequest, to
+	// set headers or start a tracing span; an error returned aborts the
+	// request, which is not retried. A panic in it is not recovered
+	BeforeRequest func(*http.Request) error
+
+	// Retain the raw XML of each response in Response.Raw, for archiving
+	// pages verbatim; off by default to save memory
+	KeepRaw bool
+	// Write the raw XML of each page of a harvest to Tee as it was
+	// received, for a snapshot to read back with ReadResponses
+	Tee io.Writer
+
+	// The cache to replay responses from and store them in, if any
+	Cache Cache
+
+	// Receives measurements of requests, retries, bytes and pages, if set
+	Metrics Metrics
+
+	// The structured logger for requests, retries, pages and harvest
+	// completion; nothing is logged when nil
+	Logger *slog.Logger
+
+	// The HTTP client used to perform the requests, http.DefaultClient if nil
+	Client *http.Client
+
+	// Transport tuning for the connections to the repository, used when
+	// no Client is given: the maximum number of idle (keep-alive) connections
+	// and how long an idle connection is kept open. The transport is built
+	// once for each tuning and shared by all the requests with that tuning
+	MaxIdleConns    int
+	IdleConnTimeout time.Duration
+
+	// How many times a request is retried when it fails to get a response
+	// or the repository replies 503 or 429 (honoring Retry-After),
+	// and the delay before the first retry, doubled for each next one
+	MaxRetries int
+	RetryDelay time.Duration
+	// Limit the rate of the requests sent, retries and the requests
+	// before a harvest included; share it between the Requests for a host
+	RateLimiter *RateLimiter
+	// Limit the rate of the requests per host, on top of RateLimiter
+	HostRateLimiter *HostRateLimiter
+
+	// The randomization of the delay between retries, none by default;
+	// a Retry-After of the repository is always honored as given
+	RetryJitter Jitter
+
+	// Drop the records delivered before in the same harvest by their
+	// identifier, keeping track of them in DedupeSet, a MemoryIdentifierSet
+	// if nil; applies to HarvestRecords and the harvests built on it
+	Dedupe    DedupeMode
+	DedupeSet IdentifierSet
+
+	// Wait this long between the pages of a harvest, as the harvesting
+	// policy of some repositories asks; not before the first page
+	PageDelay time.Duration
+
+	// Send the request without checking it against the OAI-PMH argument
+	// rules first, for testing repositories that deviate from the spec
+	SkipValidation bool
+
+	// Dry run: a harvest calls DryRun with the URL of its first request,
+	// validated and encoded exactly as it would be sent, and then stops
+	// without sending any request, nor the ones checking the repository
+	DryRun func(url string)
+
+	// The protocol version the repository must report in its Identify
+	// response before a harvest starts, usually ProtocolVersion; empty
+	// skips the check. A mismatch is logged as a warning, or fails the
+	// harvest with ErrProtocolVersion if StrictProtocolVersion is set
+	ProtocolVersion       string
+	StrictProtocolVersion bool
+
+	// Adapt From and Until to the granularity the repository advertises
+	// in its Identify response before a harvest starts, truncating
+	// datetimes to dates for a repository of day granularity, or failing
+	// if StrictGranularity is set
+	AdaptGranularity  bool
+	StrictGranularity bool
+
+	// Compare the request echoed in each response with the one sent,
+	// to catch a caching proxy serving the wrong response: a mismatch is
+	// logged as a warning, or fails with ErrEchoMismatch if StrictEcho is set
+	CheckEcho, StrictEcho bool
+
+	// Send conditional requests with the ETag and Last-Modified validators
+	// stored for the URL, treating 304 Not Modified as no change; cached
+	// responses are then revalidated rather than replayed right away
+	Validators ValidatorStore
+
+	// Check that the repository offers the MetadataPrefix with a
+	// ListMetadataFormats request before a harvest starts, failing fast
+	// with the prefixes it does offer otherwise
+	VerifyMetadataPrefix bool
+
+	// Configure the XML decoder of each response before it is used,
+	// for instance setting Strict to false and Entity to xml.HTMLEntity
+	// for a repository emitting undeclared HTML entities
+	ConfigureDecoder func(*xml.Decoder)
+
+	// Recycle the Response of each page of a harvest, and the buffer its
+	// body was read into, for the next page, which relieves the garbage
+	// collector during large harvests. The Response, its records and
+	// their slices are then only valid until the callback returns, and
+	// must be copied to be kept
+	ReuseResponses bool
+
+	// Ask for gzip compressed responses, which the repository may
+	// advertise in its Identify response; see DetectCompression
+	AcceptGzip bool
+
+	// Sign the query parameters before they are encoded into the URL,
+	// for repositories requiring for instance an HMAC of the request
+	Sign func(params url.Values) url.Values
+
+	// The answers to the checks before a harvest, shared by the requests
+	// of a larger harvest; each harvest asks its own otherwise
+	preflights *preflights
+}
+
+// The HTTP clients tuned by MaxIdleConns and IdleConnTimeout built so far,
+// one for each tuning, which all the requests with that tuning share as
+// they would share http.DefaultClient
+var tunedClients sync.Map
+
+// The tuning of the transport of an HTTP client
+type transportTuning struct {
+	maxIdleConns    int
+	idleConnTimeout time.Duration
 }
 
-// String representation of the OAI Request
+// The HTTP client for the Request: its Client, the client with a transport
+// tuned to MaxIdleConns and IdleConnTimeout, or else http.DefaultClient
+func (req *Request) httpClient() *http.Client {
+	if req.Client != nil {
+		return req.Client
+	}
+	if req.MaxIdleConns == 0 && req.IdleConnTimeout == 0 {
+		return http.DefaultClient
+	}
+
+	tuning := transportTuning{req.MaxIdleConns, req.IdleConnTimeout}
+	if client, ok := tunedClients.Load(tuning); ok {
+		return client.(*http.Client)
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if req.MaxIdleConns > 0 {
+		transport.MaxIdleConns = req.MaxIdleConns
+		transport.MaxIdleConnsPerHost = req.MaxIdleConns
+	}
+	if req.IdleConnTimeout > 0 {
+		transport.IdleConnTimeout = req.IdleConnTimeout
+	}
+	client, _ := tunedClients.LoadOrStore(tuning, &http.Client{Transport: transport})
+	return client.(*http.Client)
+}
+
+// Return a copy of the Request that can be modified without affecting
+// the original: its slices are copied, while what it refers to, such as
+// the Client, the limiters, the Cache, the Checkpoint and the DedupeSet,
+// is shared, as the harvests of a repository are meant to share them
+func (req *Request) Clone() *Request {
+	clone := *req
+	clone.IncludeSets = append([]string(nil), req.IncludeSets...)
+	clone.ExcludeSets = append([]string(nil), req.ExcludeSets...)
+	return &clone
+}
+
+// A copy of the Request for another verb, without any of its arguments,
+// to ask the same repository with the same settings
+func (req *Request) withVerb(verb Verb) *Request {
+	clone := req.Clone()
+	clone.Verb = verb
+	clone.Set, clone.MetadataPrefix, clone.Identifier = "", "", ""
+	clone.ResumptionToken, clone.From, clone.Until = "", "", ""
+	return clone
+}
+
+// String representation of the OAI Request: its URL, or the unescaped
+// base URL and arguments if the base URL is invalid
 func (req *Request) String() string {
+	if requestURL, err := req.URL(); err == nil {
+		return requestURL
+	}
+	return req.BaseUrl + "?" + strings.Join(req.query(func(value string) string { return value }), "&")
+}
+
+// The URL the Request is sent to, with its arguments escaped, without
+// performing the request; this is the exact URL to paste into a browser
+// or curl when diagnosing a repository
+func (req *Request) URL() (string, error) {
+	if req.BaseUrl == "" {
+		return "", errors.New("oai: missing base URL")
+	}
+	base, err := url.Parse(req.BaseUrl)
+	if err != nil || base.Scheme == "" || base.Host == "" {
+		return "", fmt.Errorf("oai: invalid base URL %q", req.BaseUrl)
+	}
+
+	// Keep any query string that is part of the base URL
+	if req.Sign != nil {
+		base.RawQuery = req.signedQuery(base.Query())
+	} else {
+		qs := req.query(url.QueryEscape)
+		if base.RawQuery != "" {
+			qs = append([]string{base.RawQuery}, qs...)
+		}
+		base.RawQuery = strings.Join(qs, "&")
+	}
+	base.Fragment = ""
+
+	return base.String(), nil
+}
+
+// The non-empty arguments of the Request as name=value pairs,
+// the values transformed by escape. The values are taken as they are:
+// a resumptionToken comes unescaped from the XML of the response, so
+// its %, + and & are escaped here, exactly once
+func (req *Request) query(escape func(string) string) []string {
 	qs := []string{}
 
 	add := func(name, value string) {
 		if value != "" {
-			qs = append(qs, name+"="+value)
+			qs = append(qs, name+"="+escape(value))
 		}
 	}
 
-	add("verb", req.Verb)
+	add("verb", string(req.Verb))
 	add("set", req.Set)
 	add("metadataPrefix", req.MetadataPrefix)
 	add("resumptionToken", req.ResumptionToken)
@@ -163,117 +710,439 @@ func (req *Request) String() string {
 	add("from", req.From)
 	add("until", req.Until)
 
-	return strings.Join([]string{req.BaseUrl, "?", strings.Join(qs, "&")}, "")
+	return qs
+}
+
+// The query string of the Request signed by the Sign hook, which is
+// given the arguments along with those of the base URL
+func (req *Request) signedQuery(params url.Values) string {
+	for _, pair := range req.query(func(value string) string { return value }) {
+		name, value, _ := strings.Cut(pair, "=")
+		params.Add(name, value)
+	}
+	return req.Sign(params).Encode()
 }
 
 // Perform a harvest of a complete OAI set, or simply one request
-// call the batchCallback function argument with the OAI responses
+// call the batchCallback function argument with the OAI responses,
+// one per page; see HarvestBatches for the records of each page
 func (req *Request) Harvest(batchCallback func(*Response)) {
-	// Use Perform to get the OAI response
-	oaiResponse := req.Perform()
+	if _, err := req.HarvestContext(context.Background(), batchCallback); err != nil {
+		panic(err)
+	}
+}
 
-	// Execute the callback function with the response
-	batchCallback(oaiResponse)
+// Perform a harvest of a complete OAI set, or simply one request
+// call the batchCallback function argument with the OAI responses;
+// a noRecordsMatch reply ends the harvest without calling batchCallback
+// and without error, any other failure is returned as a *HarvestError
+func (req *Request) HarvestContext(ctx context.Context, batchCallback func(*Response)) (Summary, error) {
+	start, summary := time.Now(), Summary{}
+	err := req.harvest(ctx, &summary, func(resp *Response) error {
+		for i := range resp.ListRecords.Records {
+			summary.addHeader(&resp.ListRecords.Records[i].Header)
+		}
+		for i := range resp.ListIdentifiers.Headers {
+			summary.addHeader(&resp.ListIdentifiers.Headers[i])
+		}
+		batchCallback(resp)
+		return nil
+	})
+	err = summary.finish(start, err)
+	req.logSummary(summary, err)
+	return summary, err
+}
 
-	// Check for a resumptionToken
-	hasResumptionToken, resumptionToken := oaiResponse.ResumptionToken()
+// Report the URL of the Request to the DryRun hook instead of sending it
+func (req *Request) dryRun() error {
+	if !req.SkipValidation {
+		if err := req.Validate(); err != nil {
+			return err
+		}
+	}
+	requestURL, err := req.URL()
+	if err != nil {
+		return err
+	}
+	req.DryRun(requestURL)
+	return nil
+}
 
-	// Harvest further if there is a resumption token
-	if hasResumptionToken == true {
-		req.Set = ""
-		req.MetadataPrefix = ""
-		req.From = ""
-		req.ResumptionToken = resumptionToken
-		req.Harvest(batchCallback)
+// Follow the resumption tokens from the first page of the list on,
+// calling batchCallback with each page until it returns an error
+func (req *Request) harvest(ctx context.Context, summary *Summary, batchCallback func(*Response) error) error {
+	// Paginate on a copy, leaving the caller's Request untouched
+	req = req.Clone()
+	if req.Tee != nil {
+		req.KeepRaw = true
+	}
+
+	resumed, err := req.loadCheckpoint(ctx)
+	if err != nil {
+		return err
+	}
+	if req.DryRun != nil {
+		return req.dryRun()
+	}
+	checks := req.sharedPreflights()
+	if err := req.checkProtocolVersion(ctx, summary, checks); err != nil {
+		return err
+	}
+	if err := req.checkMetadataPrefix(ctx, summary, checks); err != nil {
+		return err
+	}
+	if err := req.checkGranularity(ctx, summary, checks); err != nil {
+		return err
+	}
+
+	start := time.Now()
+	batches, records := 0, 0
+	// The tokens followed so far, to detect a repository going in circles
+	tokens := map[string]bool{req.ResumptionToken: true}
+	for {
+		// Use perform to get the OAI response
+		pageToken, truncated := req.ResumptionToken, summary.Truncated
+		oaiResponse, err := req.perform(ctx, summary)
+		if errors.Is(err, ErrNoRecordsMatch) || (errors.Is(err, ErrNotModified) && batches == 0) {
+			return nil
+		}
+		if errors.Is(err, ErrNotModified) {
+			return fmt.Errorf("%w: page %d without a cached response", err, batches+1)
+		}
+		if err != nil {
+			return err
+		}
+
+		if req.Tee != nil {
+			if _, err := req.Tee.Write(oaiResponse.Raw); err != nil {
+				return err
+			}
+		}
+
+		// Execute the callback function with the response
+		if err := batchCallback(oaiResponse); err != nil {
+			return err
+		}
+		batches++
+		records += oaiResponse.recordCount()
+		req.observeBatch(oaiResponse.recordCount())
+		req.logger().Debug("oai page decoded", slog.String("verb", string(req.Verb)),
+			slog.Int("page", batches), slog.Int("records", oaiResponse.recordCount()))
+		if req.OnProgress != nil {
+			req.OnProgress(newProgress(start, batches, records, oaiResponse))
+		}
+
+		// MaxRecords cut the page short: continuing means asking for
+		// the page again, so the checkpoint stays before it
+		if summary.Truncated && !truncated {
+			summary.ResumptionToken = pageToken
+			if req.ReuseResponses {
+				releaseResponse(oaiResponse)
+			}
+			return nil
+		}
+		if req.OnResumptionToken != nil {
+			req.OnResumptionToken(oaiResponse.Resumption())
+		}
+		if err := req.saveCheckpoint(ctx, resumed, summary, batches, records, oaiResponse.Resumption()); err != nil {
+			return err
+		}
+
+		// Check for a resumptionToken
+		hasResumptionToken, resumptionToken := oaiResponse.ResumptionToken()
+		if req.ReuseResponses {
+			releaseResponse(oaiResponse)
+		}
+
+		// Stop when the set is exhausted or a configured limit is reached
+		if !hasResumptionToken {
+			return nil
+		}
+		if req.limitReached(batches, summary.Records) {
+			summary.Truncated, summary.ResumptionToken = true, resumptionToken
+			return nil
+		}
+		if tokens[resumptionToken] {
+			return fmt.Errorf("%w: %q", ErrResumptionLoop, truncateToken(resumptionToken))
+		}
+		tokens[resumptionToken] = true
+
+		if req.PageDelay > 0 {
+			slept := time.Now()
+			err := sleep(ctx, req.PageDelay)
+			summary.Slept += time.Since(slept)
+			if err != nil {
+				return err
+			}
+		}
+
+		// Harvest further using the resumption token
+		req.logger().Debug("oai resumption token", slog.String("token", truncateToken(resumptionToken)))
+		req.resume(resumptionToken)
 	}
 }
 
-// Determine the resumption token in this Response
-func (resp *Response) ResumptionToken() (hasResumptionToken bool, resumptionToken string) {
-	hasResumptionToken = false
-	resumptionToken = ""
-	if resp == nil {
+// How the arguments of a list request are treated when the harvest
+// continues with a resumption token
+type ResumptionMode int
+
+const (
+	// Send the resumption token only, as the OAI-PMH spec demands
+	ResumptionStrict ResumptionMode = iota
+	// Send the metadataPrefix along with the resumption token,
+	// for repositories that require it to be repeated
+	ResumptionPermissive
+)
+
+// Continue a harvest from a saved resumptionToken: the arguments other
+// than the verb are cleared, as the resumptionToken is an exclusive
+// argument (the metadataPrefix is kept in ResumptionPermissive mode).
+// The token may have expired, which the repository reports as a
+// badResumptionToken error. An empty token, as saved once a harvest is
+// complete, leaves the Request as it is, to harvest the list anew
+func (req *Request) ResumeFrom(resumptionToken string) {
+	if resumptionToken == "" {
 		return
 	}
+	req.resume(resumptionToken)
+}
 
-	// First attempt to obtain a resumption token from a ListIdentifiers response
-	resumptionToken = resp.ListIdentifiers.ResumptionToken
+// Prepare the Request for the next page of the list
+func (req *Request) resume(resumptionToken string) {
+	req.Set = ""
+	req.From = ""
+	req.Until = ""
+	if req.ResumptionMode != ResumptionPermissive {
+		req.MetadataPrefix = ""
+	}
+	req.ResumptionToken = resumptionToken
+}
 
-	// Then attempt to obtain a resumption token from a ListRecords response
-	if resumptionToken == "" {
-		resumptionToken = resp.ListRecords.ResumptionToken
+// Whether the MaxBatches or MaxRecords limit has been reached, counting
+// the records delivered
+func (req *Request) limitReached(batches, records int) bool {
+	return (req.MaxBatches > 0 && batches >= req.MaxBatches) ||
+		(req.MaxRecords > 0 && records >= req.MaxRecords)
+}
+
+// Whether MaxRecords leaves no room for another record, marking the
+// Summary truncated if so: the harvest ends after the page
+func (req *Request) recordLimitReached(summary *Summary) bool {
+	if req.MaxRecords > 0 && summary.Records >= req.MaxRecords {
+		summary.Truncated = true
+		return true
 	}
+	return false
+}
 
-	// If a non-empty resumption token turned up it can safely inferred that...
-	if resumptionToken != "" {
-		hasResumptionToken = true
+// The number of records or headers contained in this Response
+func (resp *Response) recordCount() int {
+	if resp == nil {
+		return 0
 	}
+	return len(resp.ListRecords.Records) + len(resp.ListIdentifiers.Headers)
+}
 
-	return
+// Determine the resumption token in this Response; a token of only
+// white space ends the list like an empty one
+func (resp *Response) ResumptionToken() (hasResumptionToken bool, resumptionToken string) {
+	resumptionToken = strings.TrimSpace(resp.Resumption().Token)
+	return resumptionToken != "", resumptionToken
+}
+
+// Whether this Response is an incomplete list with more pages to come,
+// so a batch callback can tell the last page of a harvest
+func (resp *Response) HasMore() bool {
+	hasResumptionToken, _ := resp.ResumptionToken()
+	return hasResumptionToken
+}
+
+// The resumptionToken element of this Response with its attributes,
+// taken from the ListIdentifiers, ListRecords or ListSets part
+func (resp *Response) Resumption() ResumptionToken {
+	if resp == nil {
+		return ResumptionToken{}
+	}
+
+	for _, token := range []ResumptionToken{
+		resp.ListIdentifiers.ResumptionToken,
+		resp.ListRecords.ResumptionToken,
+		resp.ListSets.ResumptionToken,
+	} {
+		if token != (ResumptionToken{}) {
+			return token
+		}
+	}
+	return ResumptionToken{}
 }
 
 // Harvest the identifiers of a complete OAI set
 // call the identifier callback function for each Header
 func (req *Request) HarvestIdentifiers(callback func(*Header)) {
-	req.Verb = "ListIdentifiers"
-	req.Harvest(func(resp *Response) {
+	if _, err := req.HarvestIdentifiersContext(context.Background(), callback); err != nil {
+		panic(err)
+	}
+}
+
+// Harvest the identifiers of a complete OAI set
+// call the identifier callback function for each Header
+// and return the Summary of the harvest and the error that ended it, if any
+func (req *Request) HarvestIdentifiersContext(ctx context.Context, callback func(*Header)) (Summary, error) {
+	req = req.Clone()
+	req.Verb = VerbListIdentifiers
+	start, summary := time.Now(), Summary{}
+	err := req.harvestIdentifiers(ctx, &summary, func(header *Header) error {
+		callback(header)
+		return nil
+	})
+	err = summary.finish(start, err)
+	req.logSummary(summary, err)
+	return summary, err
+}
+
+// Follow the resumption tokens of a ListIdentifiers harvest, calling the
+// callback with each header passing the set filters until it returns an
+// error; the header it fails on is not counted as delivered
+func (req *Request) harvestIdentifiers(ctx context.Context, summary *Summary, callback func(*Header) error) error {
+	return req.harvest(ctx, summary, func(resp *Response) error {
 		headers := resp.ListIdentifiers.Headers
 		for _, header := range headers {
-			callback(&header)
+			if req.recordLimitReached(summary) {
+				break
+			}
+			if !req.setsAllow(&header) {
+				summary.Filtered++
+				continue
+			}
+			summary.addHeader(&header)
+			if err := callback(&header); err != nil {
+				summary.Records--
+				return err
+			}
 		}
+		return nil
 	})
 }
 
-// Harvest the identifiers of a complete OAI set
-// call the identifier callback function for each Header
+// Harvest the records of a complete OAI set
+// call the record callback function for each Record
 func (req *Request) HarvestRecords(callback func(*Record)) {
-	req.Verb = "ListRecords"
-	req.Harvest(func(resp *Response) {
-		records := resp.ListRecords.Records
-		for _, record := range records {
-			callback(&record)
-		}
+	if _, err := req.HarvestRecordsContext(context.Background(), callback); err != nil {
+		panic(err)
+	}
+}
+
+// Harvest the records of a complete OAI set
+// call the record callback function for each Record
+// and return the Summary of the harvest and the error that ended it, if any
+func (req *Request) HarvestRecordsContext(ctx context.Context, callback func(*Record)) (Summary, error) {
+	req = req.Clone()
+	req.Verb = VerbListRecords
+	start, summary := time.Now(), Summary{}
+	err := req.harvestRecords(ctx, &summary, func(record *Record) error {
+		callback(record)
+		return nil
 	})
+	err = summary.finish(start, err)
+	req.logSummary(summary, err)
+	return summary, err
+}
+
+// Follow the resumption tokens of a ListRecords harvest, calling the
+// callback with each record passing the filters until it returns an error
+func (req *Request) harvestRecords(ctx context.Context, summary *Summary, callback func(*Record) error) error {
+	if err := req.initDedupe(); err != nil {
+		return err
+	}
+	return req.harvest(ctx, summary, func(resp *Response) error {
+		return req.deliverRecords(summary, resp.ListRecords.Records, callback)
+	})
+}
+
+// Call the callback with each of the records of a page passing the
+// filters, up to MaxRecords, until it returns an error; the record it
+// fails on is not counted as delivered
+func (req *Request) deliverRecords(summary *Summary, records []Record, callback func(*Record) error) error {
+	for _, record := range records {
+		if req.recordLimitReached(summary) {
+			break
+		}
+		if !req.setsAllow(&record.Header) {
+			summary.Filtered++
+			continue
+		}
+		if req.RecordFilter != nil && !req.RecordFilter(&record) {
+			summary.Rejected++
+			continue
+		}
+		if req.Dedupe != DedupeOff {
+			duplicate, err := req.duplicate(&record)
+			if err != nil {
+				return err
+			}
+			if duplicate {
+				summary.Duplicates++
+				continue
+			}
+		}
+		var err error
+		req.deliver(summary, &record, func(record *Record) { err = callback(record) })
+		if err != nil {
+			summary.Records--
+			return err
+		}
+	}
+	return nil
 }
 
 // Reads OAI PMH response XML from a file
-func FromFile(filename string) (oaiResponse *Response) {
+func FromFile(filename string) (*Response, error) {
 	bytes, err := ioutil.ReadFile(filename)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
 	// Unmarshall all the data
-	err = xml.Unmarshal(bytes, &oaiResponse)
-	if err != nil {
-		panic(err)
-	}
-
-	return
+	return decodeResponse(bytes, nil)
 }
 
 // Harvest the identifiers of a complete OAI set
 // send a reference of each Header to a channel
 func (req *Request) ChannelHarvestIdentifiers(channels []chan *Header) {
-	req.Verb = "ListIdentifiers"
-	req.Harvest(func(resp *Response) {
-		headers := resp.ListIdentifiers.Headers
-		i := 0
-		for _, header := range headers {
-			channels[i] <- &header
-			i++
-			if i == len(channels) {
-				i = 0
-			}
-		}
+	if _, err := req.ChannelHarvestIdentifiersContext(context.Background(), channels); err != nil {
+		panic(err)
+	}
+}
 
-		// If there is no more resumption token, send nil to all
-		// the channels to signal the harvest is done
-		hasResumptionToken, _ := resp.ResumptionToken()
-		if !hasResumptionToken {
-			for _, channel := range channels {
-				channel <- nil
-			}
+// Harvest the identifiers of a complete OAI set
+// send a reference of each Header to a channel, round robin, and nil to
+// all of them when the harvest is over
+// and return the Summary of the harvest and the error that ended it, if any
+//
+// When the context is cancelled, the request in flight is cancelled, the
+// remaining headers are dropped instead of waiting for a receiver and no
+// nil is sent, so receivers must watch the context as well
+func (req *Request) ChannelHarvestIdentifiersContext(ctx context.Context, channels []chan *Header) (Summary, error) {
+	i := 0
+	summary, err := req.HarvestIdentifiersContext(ctx, func(header *Header) {
+		select {
+		case channels[i] <- header:
+		case <-ctx.Done():
+			return
+		}
+		i++
+		if i == len(channels) {
+			i = 0
 		}
 	})
+
+	// The harvest is done, send nil to all the channels to signal it
+	for _, channel := range channels {
+		select {
+		case channel <- nil:
+		case <-ctx.Done():
+			return summary, err
+		}
+	}
+	return summary, err
 }
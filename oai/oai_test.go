@@ -0,0 +1,486 @@
+package oai
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// A record of the testRepository
+type testRecord struct {
+	identifier string
+	datestamp  string
+	sets       []string
+	deleted    bool
+}
+
+// An OAI-PMH repository for the tests, listing its records a page of
+// pageSize at a time; the resumptionToken is the offset of the next page
+// followed by the set, from and until arguments, separated by slashes
+type testRepository struct {
+	records  []testRecord
+	pageSize int
+	// The granularity Identify advertises, seconds if empty
+	granularity Granularity
+
+	mu       sync.Mutex
+	requests []url.Values
+}
+
+// A testRepository with n records oai:test:0 and on, an hour apart from
+// 2024-01-01 on, in the set even or odd, with every deleted-th one deleted
+func newTestRepository(n, pageSize, deleted int) *testRepository {
+	repo := &testRepository{pageSize: pageSize}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < n; i++ {
+		set := "even"
+		if i%2 == 1 {
+			set = "odd"
+		}
+		repo.records = append(repo.records, testRecord{
+			identifier: fmt.Sprintf("oai:test:%d", i),
+			datestamp:  GranularitySecond.Format(start.Add(time.Duration(i) * time.Hour)),
+			sets:       []string{set},
+			deleted:    deleted > 0 && i%deleted == deleted-1,
+		})
+	}
+	return repo
+}
+
+// Serve the repository with an httptest server, closed when the test ends
+func (repo *testRepository) serve(t *testing.T) *httptest.Server {
+	return httptestServer(t, repo)
+}
+
+// The query of each request served so far
+func (repo *testRepository) served() []url.Values {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	return append([]url.Values{}, repo.requests...)
+}
+
+func (repo *testRepository) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	repo.mu.Lock()
+	repo.requests = append(repo.requests, query)
+	repo.mu.Unlock()
+
+	var body bytes.Buffer
+	body.WriteString(xml.Header)
+	body.WriteString(`<OAI-PMH xmlns="http://www.openarchives.org/OAI/2.0/"><responseDate>2024-06-01T00:00:00Z</responseDate>`)
+	fmt.Fprintf(&body, `<request verb="%s">%s</request>`, escape(query.Get("verb")), escape("http://"+r.Host+r.URL.Path))
+	switch query.Get("verb") {
+	case "Identify":
+		granularity := repo.granularity
+		if granularity == "" {
+			granularity = GranularitySecond
+		}
+		body.WriteString(`<Identify><repositoryName>Test</repositoryName><baseURL>http://` + escape(r.Host) + `</baseURL>` +
+			`<protocolVersion>2.0</protocolVersion><adminEmail>test@example.org</adminEmail>` +
+			`<earliestDatestamp>2024-01-01T00:00:00Z</earliestDatestamp><deletedRecord>persistent</deletedRecord>` +
+			`<granularity>` + string(granularity) + `</granularity></Identify>`)
+	case "ListMetadataFormats":
+		body.WriteString(`<ListMetadataFormats><metadataFormat><metadataPrefix>oai_dc</metadataPrefix>` +
+			`<schema>http://www.openarchives.org/OAI/2.0/oai_dc.xsd</schema>` +
+			`<metadataNamespace>http://www.openarchives.org/OAI/2.0/oai_dc/</metadataNamespace></metadataFormat></ListMetadataFormats>`)
+	case "GetRecord":
+		for _, record := range repo.records {
+			if record.identifier == query.Get("identifier") {
+				body.WriteString(`<GetRecord>` + record.xml(true) + `</GetRecord>`)
+				break
+			}
+		}
+		if !strings.Contains(body.String(), "<GetRecord>") {
+			body.WriteString(`<error code="idDoesNotExist">no such record</error>`)
+		}
+	case "ListSets":
+		body.WriteString("<ListSets>")
+		specs := map[string]bool{}
+		for _, record := range repo.records {
+			for _, spec := range record.sets {
+				if !specs[spec] {
+					specs[spec] = true
+					body.WriteString("<set><setSpec>" + escape(spec) + "</setSpec><setName>" + escape(spec) + "</setName></set>")
+				}
+			}
+		}
+		body.WriteString("</ListSets>")
+	case "ListRecords", "ListIdentifiers":
+		repo.list(&body, query)
+	default:
+		body.WriteString(`<error code="badVerb">illegal verb</error>`)
+	}
+	body.WriteString(`</OAI-PMH>`)
+
+	w.Header().Set("Content-Type", "text/xml")
+	w.Write(body.Bytes())
+}
+
+// Write a page of a ListRecords or ListIdentifiers response
+func (repo *testRepository) list(body *bytes.Buffer, query url.Values) {
+	offset, set, from, until := 0, query.Get("set"), query.Get("from"), query.Get("until")
+	if token := query.Get("resumptionToken"); token != "" {
+		parts := strings.SplitN(token, "/", 4)
+		if len(parts) != 4 {
+			body.WriteString(`<error code="badResumptionToken">bad token</error>`)
+			return
+		}
+		offset, _ = strconv.Atoi(parts[0])
+		set, from, until = parts[1], parts[2], parts[3]
+	}
+
+	matching := []testRecord{}
+	for _, record := range repo.records {
+		if (set == "" || record.inSet(set)) && (from == "" || record.datestamp >= from) &&
+			(until == "" || record.datestamp <= until || strings.HasPrefix(record.datestamp, until)) {
+			matching = append(matching, record)
+		}
+	}
+	if len(matching) == 0 {
+		body.WriteString(`<error code="noRecordsMatch">no records</error>`)
+		return
+	}
+
+	verb := query.Get("verb")
+	body.WriteString("<" + verb + ">")
+	end := offset + repo.pageSize
+	if end > len(matching) {
+		end = len(matching)
+	}
+	for _, record := range matching[offset:end] {
+		if verb == "ListRecords" {
+			body.WriteString(record.xml(true))
+		} else {
+			body.WriteString(record.xml(false))
+		}
+	}
+	if end < len(matching) {
+		token := fmt.Sprintf("%d/%s/%s/%s", end, set, from, until)
+		fmt.Fprintf(body, `<resumptionToken completeListSize="%d" cursor="%d">%s</resumptionToken>`, len(matching), offset, escape(token))
+	} else if offset > 0 {
+		fmt.Fprintf(body, `<resumptionToken completeListSize="%d" cursor="%d"/>`, len(matching), offset)
+	}
+	body.WriteString("</" + verb + ">")
+}
+
+func (record testRecord) inSet(set string) bool {
+	for _, spec := range record.sets {
+		if spec == set {
+			return true
+		}
+	}
+	return false
+}
+
+// The record element, or the header element only
+func (record testRecord) xml(full bool) string {
+	var header strings.Builder
+	header.WriteString("<header")
+	if record.deleted {
+		header.WriteString(` status="deleted"`)
+	}
+	header.WriteString("><identifier>" + escape(record.identifier) + "</identifier><datestamp>" + record.datestamp + "</datestamp>")
+	for _, set := range record.sets {
+		header.WriteString("<setSpec>" + escape(set) + "</setSpec>")
+	}
+	header.WriteString("</header>")
+	if !full {
+		return header.String()
+	}
+	if record.deleted {
+		return "<record>" + header.String() + "</record>"
+	}
+	return "<record>" + header.String() + `<metadata><oai_dc:dc xmlns:oai_dc="http://www.openarchives.org/OAI/2.0/oai_dc/" ` +
+		`xmlns:dc="http://purl.org/dc/elements/1.1/"><dc:title>Record ` + escape(record.identifier) +
+		`</dc:title></oai_dc:dc></metadata></record>`
+}
+
+func escape(s string) string {
+	var escaped strings.Builder
+	xml.EscapeText(&escaped, []byte(s))
+	return escaped.String()
+}
+
+// An httptest server for the handler, closed when the test ends
+func httptestServer(t *testing.T, handler http.Handler) *httptest.Server {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// A Request for the oai_dc records of the server
+func testRequest(server *httptest.Server) *Request {
+	return NewRequest(server.URL, WithMetadataPrefix("oai_dc"))
+}
+
+// The identifiers of the records
+func identifiers(records []*Record) []string {
+	ids := []string{}
+	for _, record := range records {
+		ids = append(ids, record.Header.Identifier)
+	}
+	return ids
+}
+
+func TestHarvestRecordsFollowsResumptionTokens(t *testing.T) {
+	repo := newTestRepository(10, 3, 0)
+	server := repo.serve(t)
+
+	var records []*Record
+	summary, err := testRequest(server).HarvestRecordsContext(context.Background(), func(record *Record) {
+		records = append(records, record)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 10 || summary.Records != 10 || summary.Requests != 4 {
+		t.Fatalf("got %d records, summary %d records in %d requests, want 10 in 4",
+			len(records), summary.Records, summary.Requests)
+	}
+	if records[9].Header.Identifier != "oai:test:9" {
+		t.Errorf("last record %s, want oai:test:9", records[9].Header.Identifier)
+	}
+}
+
+func TestMaxRecordsCuttingTheLastPageTruncates(t *testing.T) {
+	server := newTestRepository(10, 10, 0).serve(t)
+	req := testRequest(server)
+	req.MaxRecords = 5
+
+	var records []*Record
+	summary, err := req.HarvestRecordsContext(context.Background(), func(record *Record) {
+		records = append(records, record)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 5 || !summary.Truncated || summary.ResumptionToken != "" {
+		t.Fatalf("got %d records, truncated %v, token %q, want 5, true and the token of the first page",
+			len(records), summary.Truncated, summary.ResumptionToken)
+	}
+}
+
+func TestContinuingAfterMaxRecordsLosesNothing(t *testing.T) {
+	server := newTestRepository(10, 3, 0).serve(t)
+	req := testRequest(server)
+	req.MaxRecords = 5
+
+	delivered := map[string]bool{}
+	summary, err := req.HarvestRecordsContext(context.Background(), func(record *Record) {
+		delivered[record.Header.Identifier] = true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !summary.Truncated || summary.ResumptionToken == "" {
+		t.Fatalf("truncated %v, token %q", summary.Truncated, summary.ResumptionToken)
+	}
+
+	req.MaxRecords = 0
+	req.ResumeFrom(summary.ResumptionToken)
+	if _, err := req.HarvestRecordsContext(context.Background(), func(record *Record) {
+		delivered[record.Header.Identifier] = true
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(delivered) != 10 {
+		t.Errorf("delivered %d distinct records in all, want 10", len(delivered))
+	}
+}
+
+func TestMaxRecordsCountsDeliveredRecords(t *testing.T) {
+	server := newTestRepository(10, 2, 0).serve(t)
+	req := testRequest(server)
+	req.MaxRecords = 3
+	req.FilterSets([]string{"even"}, nil)
+
+	var records []*Record
+	summary, err := req.HarvestRecordsContext(context.Background(), func(record *Record) {
+		records = append(records, record)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(identifiers(records), " "); got != "oai:test:0 oai:test:2 oai:test:4" {
+		t.Errorf("delivered %s", got)
+	}
+	if !summary.Truncated {
+		t.Error("not truncated")
+	}
+}
+
+func TestResumptionTokenRoundTrip(t *testing.T) {
+	const token = "a%25b+c&d=e f/%"
+	for _, signed := range []bool{false, true} {
+		var received []string
+		server := httptestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			received = append(received, r.URL.Query().Get("resumptionToken"))
+			next := ""
+			if len(received) == 1 {
+				next = token
+			}
+			fmt.Fprintf(w, `<OAI-PMH><ListRecords><record><header><identifier>oai:test:%d</identifier></header></record>`+
+				`<resumptionToken>%s</resumptionToken></ListRecords></OAI-PMH>`, len(received), escape(next))
+		}))
+		req := testRequest(server)
+		if signed {
+			req.Sign = func(params url.Values) url.Values { return params }
+		}
+		if _, err := req.HarvestRecordsContext(context.Background(), func(*Record) {}); err != nil {
+			t.Fatal(err)
+		}
+		if len(received) != 2 || received[1] != token {
+			t.Errorf("signed %v: server received tokens %q, want %q second", signed, received, token)
+		}
+	}
+}
+
+func TestCloneCopiesSetFilters(t *testing.T) {
+	req := &Request{}
+	req.FilterSets([]string{"even"}, []string{"odd"})
+	clone := req.Clone()
+	clone.IncludeSets[0], clone.ExcludeSets[0] = "odd", "even"
+	if req.IncludeSets[0] != "even" || req.ExcludeSets[0] != "odd" {
+		t.Errorf("changing the clone changed the original to %v, %v", req.IncludeSets, req.ExcludeSets)
+	}
+}
+
+func TestConcurrentHarvestsFromClones(t *testing.T) {
+	server := newTestRepository(20, 3, 0).serve(t)
+	req := testRequest(server)
+	req.RateLimiter = NewRateLimiter(0, 0)
+	req.FilterSets([]string{"even"}, nil)
+
+	var wg sync.WaitGroup
+	delivered := make([][]*Record, 2)
+	errs := make([]error, 2)
+	for i, set := range []string{"even", "odd"} {
+		wg.Add(1)
+		go func(i int, set string) {
+			defer wg.Done()
+			clone := req.Clone()
+			clone.IncludeSets[0] = set
+			_, errs[i] = clone.HarvestRecordsContext(context.Background(), func(record *Record) {
+				delivered[i] = append(delivered[i], record)
+			})
+		}(i, set)
+	}
+	wg.Wait()
+
+	for i, set := range []string{"even", "odd"} {
+		if errs[i] != nil {
+			t.Fatalf("%s: %v", set, errs[i])
+		}
+		if len(delivered[i]) != 10 {
+			t.Errorf("%s: delivered %v", set, identifiers(delivered[i]))
+		}
+		for _, record := range delivered[i] {
+			if !record.InSet(set) {
+				t.Errorf("%s: delivered %s of %v", set, record.Header.Identifier, record.Sets())
+			}
+		}
+	}
+}
+
+func TestTunedTransportIsReused(t *testing.T) {
+	repo := newTestRepository(4, 2, 0)
+	server := httptest.NewUnstartedServer(repo)
+	var mu sync.Mutex
+	connections := 0
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			mu.Lock()
+			connections++
+			mu.Unlock()
+		}
+	}
+	server.Start()
+	t.Cleanup(server.Close)
+
+	req := testRequest(server)
+	req.MaxIdleConns, req.IdleConnTimeout = 2, 42*time.Second
+	if req.httpClient() != req.Clone().httpClient() {
+		t.Error("a clone with the same tuning got another client")
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := req.withVerb(VerbIdentify).PerformContext(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := req.HarvestRecordsContext(context.Background(), func(*Record) {}); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if connections != 1 {
+		t.Errorf("%d connections for %d requests, want 1", connections, len(repo.served()))
+	}
+}
+
+func TestResumeFromEmptyTokenKeepsTheArguments(t *testing.T) {
+	server := newTestRepository(3, 10, 0).serve(t)
+	req := testRequest(server)
+	req.Set = "even"
+	req.ResumeFrom("")
+	if req.MetadataPrefix != "oai_dc" || req.Set != "even" {
+		t.Fatalf("metadataPrefix %q and set %q after resuming from an empty token", req.MetadataPrefix, req.Set)
+	}
+
+	summary, err := req.HarvestRecordsContext(context.Background(), func(*Record) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Records != 2 {
+		t.Errorf("%d records, want the 2 of set even", summary.Records)
+	}
+}
+
+func TestResumptionPermissiveRepeatsTheMetadataPrefix(t *testing.T) {
+	for _, mode := range []ResumptionMode{ResumptionStrict, ResumptionPermissive} {
+		repo := newTestRepository(10, 3, 0)
+		server := repo.serve(t)
+		req := testRequest(server)
+		req.Set = "even"
+		req.ResumptionMode = mode
+
+		summary, err := req.HarvestRecordsContext(context.Background(), func(*Record) {})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if summary.Records != 5 {
+			t.Errorf("mode %d: %d records, want the 5 of set even", mode, summary.Records)
+		}
+		resumed := 0
+		for _, query := range repo.served() {
+			if query.Get("resumptionToken") == "" {
+				continue
+			}
+			resumed++
+			if query.Get("set") != "" || (query.Get("metadataPrefix") != "") != (mode == ResumptionPermissive) {
+				t.Errorf("mode %d: resumed with %v", mode, query)
+			}
+		}
+		if resumed != 1 {
+			t.Errorf("mode %d: %d requests with a resumptionToken, want 1", mode, resumed)
+		}
+
+		req.ResumeFrom("3/even//")
+		if req.Set != "" || (req.MetadataPrefix == "oai_dc") != (mode == ResumptionPermissive) {
+			t.Errorf("mode %d: set %q and metadataPrefix %q after ResumeFrom", mode, req.Set, req.MetadataPrefix)
+		}
+		if err := req.Validate(); err != nil {
+			t.Errorf("mode %d: %v", mode, err)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+// Package oaidc provides the unqualified Dublin Core (oai_dc) metadata
+// format, which every OAI-PMH repository must support
+package oaidc
+
+import "encoding/xml"
+
+const (
+	// The metadataPrefix of the format
+	MetadataPrefix = "oai_dc"
+	// The namespace of the oai_dc container element
+	Namespace = "http://www.openarchives.org/OAI/2.0/oai_dc/"
+	// The namespace of the fifteen Dublin Core elements
+	ElementsNamespace = "http://purl.org/dc/elements/1.1/"
+)
+
+// The fifteen Dublin Core elements, each of which may occur any number
+// of times; the elements are matched by local name, so records that get
+// the namespaces wrong still decode
+type DublinCore struct {
+	Title       []string `xml:"title"`
+	Creator     []string `xml:"creator"`
+	Subject     []string `xml:"subject"`
+	Description []string `xml:"description"`
+	Publisher   []string `xml:"publisher"`
+	Contributor []string `xml:"contributor"`
+	Date        []string `xml:"date"`
+	Type        []string `xml:"type"`
+	Format      []string `xml:"format"`
+	Identifier  []string `xml:"identifier"`
+	Source      []string `xml:"source"`
+	Language    []string `xml:"language"`
+	Relation    []string `xml:"relation"`
+	Coverage    []string `xml:"coverage"`
+	Rights      []string `xml:"rights"`
+}
+
+// Decode an oai_dc element
+func Decode(data []byte) (*DublinCore, error) {
+	dc := &DublinCore{}
+	if err := xml.Unmarshal(data, dc); err != nil {
+		return nil, err
+	}
+	return dc, nil
+}
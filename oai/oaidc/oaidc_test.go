@@ -0,0 +1,49 @@
+package oaidc
+
+import (
+	"io/ioutil"
+	"reflect"
+	"testing"
+)
+
+func TestDecode(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/record.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dc, err := Decode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &DublinCore{
+		Title:       []string{"On the origin of species", "Über die Entstehung der Arten"},
+		Creator:     []string{"Darwin, Charles"},
+		Subject:     []string{"Evolution", "Natural selection", "576.8"},
+		Description: []string{"By means of natural selection, or the preservation of favoured races in the struggle for life."},
+		Publisher:   []string{"John Murray"},
+		Contributor: []string{"Murray, John"},
+		Date:        []string{"1859"},
+		Type:        []string{"Text", "Book"},
+		Format:      []string{"application/pdf"},
+		Identifier:  []string{"https://example.org/darwin/origin", "urn:isbn:978-0-00-000000-0"},
+		Source:      []string{"Library copy"},
+		Language:    []string{"eng"},
+		Relation:    []string{"https://example.org/darwin"},
+		Coverage:    []string{"19th century"},
+		Rights:      []string{"Public domain"},
+	}
+	if !reflect.DeepEqual(dc, want) {
+		t.Errorf("decoded\n%#v\nwant\n%#v", dc, want)
+	}
+}
+
+func TestDecodeWithoutNamespaces(t *testing.T) {
+	dc, err := Decode([]byte(`<dc><title>Untitled</title><creator>Anonymous</creator><creator>Unknown</creator></dc>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dc.Title) != 1 || dc.Title[0] != "Untitled" || len(dc.Creator) != 2 {
+		t.Errorf("decoded %#v", dc)
+	}
+}
@@ -0,0 +1,110 @@
+package oai
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// A setting of a Request made by NewRequest
+type Option func(*Request)
+
+// A Request for the repository at baseURL, a ListRecords request unless
+// WithVerb says otherwise, with the options applied in order
+func NewRequest(baseURL string, opts ...Option) *Request {
+	req := &Request{BaseUrl: baseURL, Verb: VerbListRecords}
+	for _, opt := range opts {
+		opt(req)
+	}
+	return req
+}
+
+// Send the given verb
+func WithVerb(verb Verb) Option {
+	return func(req *Request) { req.Verb = verb }
+}
+
+// Harvest the set with the given setSpec
+func WithSet(spec string) Option {
+	return func(req *Request) { req.Set = spec }
+}
+
+// Harvest the metadata in the format with the given prefix
+func WithMetadataPrefix(prefix string) Option {
+	return func(req *Request) { req.MetadataPrefix = prefix }
+}
+
+// Ask for the record with the given identifier
+func WithIdentifier(identifier string) Option {
+	return func(req *Request) { req.Identifier = identifier }
+}
+
+// Harvest the records changed at or after from
+func WithFrom(from time.Time) Option {
+	return func(req *Request) { req.SetFrom(from) }
+}
+
+// Harvest the records changed at or before until
+func WithUntil(until time.Time) Option {
+	return func(req *Request) { req.SetUntil(until) }
+}
+
+// Format from and until with the given granularity, including those
+// set by an earlier WithFrom or WithUntil
+func WithGranularity(granularity Granularity) Option {
+	return func(req *Request) {
+		req.Granularity = granularity
+		for _, datestamp := range []*string{&req.From, &req.Until} {
+			if t, err := ParseDatestamp(*datestamp); err == nil {
+				*datestamp = granularity.Format(t)
+			}
+		}
+	}
+}
+
+// Stop a harvest after n records
+func WithMaxRecords(n int) Option {
+	return func(req *Request) { req.MaxRecords = n }
+}
+
+// Stop a harvest after n pages
+func WithMaxPages(n int) Option {
+	return func(req *Request) { req.MaxBatches = n }
+}
+
+// Deliver only the records for which filter returns true
+func WithRecordFilter(filter func(*Record) bool) Option {
+	return func(req *Request) { req.RecordFilter = filter }
+}
+
+// Send the requests with the given client
+func WithClient(client *http.Client) Option {
+	return func(req *Request) { req.Client = client }
+}
+
+// Retry a failed request up to retries times, waiting delay before
+// the first retry, doubled for each next one
+func WithRetries(retries int, delay time.Duration) Option {
+	return func(req *Request) { req.MaxRetries, req.RetryDelay = retries, delay }
+}
+
+// Limit the rate of the requests with the given RateLimiter
+func WithRateLimiter(limiter *RateLimiter) Option {
+	return func(req *Request) { req.RateLimiter = limiter }
+}
+
+// Wait the given delay between the pages of a harvest
+func WithPageDelay(delay time.Duration) Option {
+	return func(req *Request) { req.PageDelay = delay }
+}
+
+// Report the URL of the first request of a harvest to dryRun
+// instead of sending any request
+func WithDryRun(dryRun func(url string)) Option {
+	return func(req *Request) { req.DryRun = dryRun }
+}
+
+// Log to the given Logger
+func WithLogger(logger *slog.Logger) Option {
+	return func(req *Request) { req.Logger = logger }
+}
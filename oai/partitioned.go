@@ -0,0 +1,139 @@
+package oai
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Harvest the records of the repository by harvesting its top-level sets
+// concurrently, using the given number of workers
+// call the record callback function for each Record
+func (req *Request) HarvestPartitioned(workers int, callback func(*Record)) {
+	if _, err := req.HarvestPartitionedContext(context.Background(), workers, callback); err != nil {
+		panic(err)
+	}
+}
+
+// Harvest the records of the repository by harvesting its top-level sets
+// concurrently, using the given number of workers, each following the
+// resumption tokens of its own set, to speed up the harvest of huge
+// repositories
+// call the record callback function for each Record, one call at a time,
+// and once per identifier even if it is a member of several sets, as
+// deduplicated by the Dedupe mode (DedupeFirst if off) and DedupeSet
+// and return the Summary of the harvest and the error that ended it, if any
+//
+// Records that belong to no set are not harvested. The Set of the Request
+// is ignored, MaxRecords applies to the whole harvest, MaxBatches and the
+// resumption token of the Request do not apply, and the first set failing
+// to harvest stops the others
+func (req *Request) HarvestPartitionedContext(ctx context.Context, workers int, callback func(*Record)) (Summary, error) {
+	req = req.Clone()
+	req.preflights = req.sharedPreflights()
+	if workers < 1 {
+		workers = 1
+	}
+	start, summary := time.Now(), Summary{}
+
+	// Records of several sets are delivered once, keeping track of them
+	// in the DedupeSet rather than in memory if the caller sets one
+	if req.Dedupe == DedupeOff {
+		req.Dedupe = DedupeFirst
+	}
+	err := req.initDedupe()
+	var sets []Set
+	if err == nil {
+		sets, err = req.listSets(ctx, &summary)
+	}
+	if err != nil {
+		err = summary.finish(start, err)
+		req.logSummary(summary, err)
+		return summary, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg         sync.WaitGroup
+		mu         sync.Mutex
+		harvestErr error
+		limited    bool
+		delivered  Summary
+	)
+	specs := make(chan string)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for spec := range specs {
+				setReq := req.Clone()
+				setReq.Verb = VerbListRecords
+				setReq.Set = spec
+				setReq.MaxRecords, setReq.MaxBatches = 0, 0
+				setReq.ResumptionToken, setReq.OnResumptionToken = "", nil
+				setReq.Tee, setReq.OnProgress = nil, nil
+				setReq.SkipDeleted, setReq.OnDeleted = false, nil
+				setReq.Dedupe = DedupeOff
+				setReq.Checkpoint = nil
+				setStart, setSummary := time.Now(), Summary{}
+				err := setReq.harvestRecords(ctx, &setSummary, func(record *Record) error {
+					mu.Lock()
+					defer mu.Unlock()
+					if limited {
+						return nil
+					}
+					duplicate, err := req.duplicate(record)
+					if err != nil {
+						return err
+					}
+					if duplicate {
+						delivered.Duplicates++
+						return nil
+					}
+					req.deliver(&delivered, record, callback)
+					if req.MaxRecords > 0 && delivered.Records >= req.MaxRecords {
+						limited = true
+						cancel()
+					}
+					return nil
+				})
+				err = setSummary.finish(setStart, err)
+
+				mu.Lock()
+				summary.merge(setSummary)
+				if err != nil && harvestErr == nil && !limited {
+					harvestErr = errors.Unwrap(err)
+					cancel()
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+dispatch:
+	for _, set := range sets {
+		if strings.Contains(set.SetSpec, ":") {
+			continue
+		}
+		select {
+		case specs <- set.SetSpec:
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(specs)
+	wg.Wait()
+
+	// Count the records delivered, once each
+	summary.Records, summary.Deleted = delivered.Records, delivered.Deleted
+	summary.Duplicates += delivered.Duplicates
+	summary.FirstDatestamp, summary.LastDatestamp = delivered.FirstDatestamp, delivered.LastDatestamp
+	summary.Truncated = summary.Truncated || limited
+	err = summary.finish(start, harvestErr)
+	req.logSummary(summary, err)
+	return summary, err
+}
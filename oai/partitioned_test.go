@@ -0,0 +1,43 @@
+package oai
+
+import (
+	"bytes"
+	"context"
+	"testing"
+)
+
+func TestHarvestPartitionedDeliversEachRecordOnce(t *testing.T) {
+	repo := newIntersectionRepository()
+	repo.pageSize = 2
+	server := repo.serve(t)
+
+	req := testRequest(server)
+	req.DedupeSet = &MemoryIdentifierSet{}
+	var tee bytes.Buffer
+	req.Tee = &tee
+	req.MaxBatches = 1
+	req.OnResumptionToken = func(token ResumptionToken) { t.Errorf("set resumption token %q reported", token.Token) }
+
+	delivered := map[string]int{}
+	summary, err := req.HarvestPartitionedContext(context.Background(), 3, func(record *Record) {
+		delivered[record.Header.Identifier]++
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for identifier, n := range delivered {
+		if n > 1 {
+			t.Errorf("%s delivered %d times", identifier, n)
+		}
+	}
+	if len(delivered) != 12 || summary.Records != 12 || summary.Deleted != 4 || summary.Duplicates != 3 {
+		t.Errorf("delivered %d records, summary of %d with %d deleted and %d duplicates, want 12 with 4 and 3",
+			len(delivered), summary.Records, summary.Deleted, summary.Duplicates)
+	}
+	if member, _ := req.DedupeSet.Contains("oai:test:11"); !member {
+		t.Error("identifiers not kept in the DedupeSet of the Request")
+	}
+	if tee.Len() > 0 {
+		t.Errorf("pages of the partitions written to the Tee of the Request: %.100s", tee.String())
+	}
+}
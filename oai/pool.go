@@ -0,0 +1,51 @@
+package oai
+
+import (
+	"bytes"
+	"sync"
+)
+
+// Responses and response body buffers recycled between the pages of
+// harvests with ReuseResponses set
+var (
+	responsePool = sync.Pool{New: func() interface{} { return &Response{} }}
+	bufferPool   = sync.Pool{New: func() interface{} { return &bytes.Buffer{} }}
+)
+
+// Whether the response body buffers can be recycled: only when nothing
+// but the decoder gets to see them
+func (req *Request) reuseBuffers() bool {
+	return req.ReuseResponses && req.Cache == nil && !req.KeepRaw && req.OnResponse == nil
+}
+
+// A Response to decode into, recycled if one is available
+func newResponse() *Response {
+	return responsePool.Get().(*Response)
+}
+
+// Recycle the Response, keeping the capacity of its largest slices;
+// the elements are zeroed, as decoding appends to a slice in place
+func releaseResponse(resp *Response) {
+	records, headers, sets := resp.ListRecords.Records, resp.ListIdentifiers.Headers, resp.ListSets.Set
+	clear(records)
+	clear(headers)
+	clear(sets)
+
+	*resp = Response{}
+	resp.ListRecords.Records = records[:0]
+	resp.ListIdentifiers.Headers = headers[:0]
+	resp.ListSets.Set = sets[:0]
+	responsePool.Put(resp)
+}
+
+// A buffer to read a response body into
+func newBuffer() *bytes.Buffer {
+	buffer := bufferPool.Get().(*bytes.Buffer)
+	buffer.Reset()
+	return buffer
+}
+
+// Recycle the memory of a response body read with newBuffer
+func releaseBody(body []byte) {
+	bufferPool.Put(bytes.NewBuffer(body[:0]))
+}
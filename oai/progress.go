@@ -0,0 +1,47 @@
+package oai
+
+import "time"
+
+// The progress of a harvest, reported after each page
+type Progress struct {
+	// The number of pages harvested so far, starting at 1
+	Page int
+	// The number of records or headers in this page
+	PageRecords int
+	// The number of records or headers harvested so far
+	Records int
+
+	// The cursor and completeListSize of the resumption token,
+	// zero when the repository does not provide them
+	Cursor, CompleteListSize int
+
+	// The time since the harvest started, and the estimated time until
+	// it completes (zero when the completeListSize is unknown)
+	Elapsed, ETA time.Duration
+}
+
+// Compute the progress after a page of the harvest started at start
+func newProgress(start time.Time, page, records int, resp *Response) Progress {
+	token := resp.Resumption()
+	progress := Progress{
+		Page:             page,
+		PageRecords:      resp.recordCount(),
+		Records:          records,
+		Cursor:           token.Cursor,
+		CompleteListSize: token.CompleteListSize,
+		Elapsed:          time.Since(start),
+	}
+
+	// The position in the complete list, which is ahead of the number of
+	// records harvested when the harvest was resumed halfway
+	done := progress.Records
+	if progress.Cursor > 0 {
+		done = progress.Cursor + progress.PageRecords
+	}
+
+	if progress.CompleteListSize > done && progress.Records > 0 {
+		perRecord := progress.Elapsed / time.Duration(progress.Records)
+		progress.ETA = perRecord * time.Duration(progress.CompleteListSize-done)
+	}
+	return progress
+}
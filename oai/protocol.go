@@ -0,0 +1,95 @@
+package oai
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+)
+
+// The OAI-PMH version this package implements
+const ProtocolVersion = "2.0"
+
+// Ask the repository for its protocol version and compare it with the
+// ProtocolVersion of the Request; older repositories claiming 1.1 differ
+// in their error codes and datestamp handling
+func (req *Request) checkProtocolVersion(ctx context.Context, summary *Summary, checks *preflights) error {
+	if req.ProtocolVersion == "" {
+		return nil
+	}
+
+	identify, err := checks.identifyRepository(ctx, req, summary)
+	if err != nil {
+		return err
+	}
+
+	version := strings.TrimSpace(identify.ProtocolVersion)
+	if version == req.ProtocolVersion {
+		return nil
+	}
+	if req.StrictProtocolVersion {
+		return fmt.Errorf("%w: repository reports %q, expected %q", ErrProtocolVersion, version, req.ProtocolVersion)
+	}
+	req.logger().Warn("oai protocol version mismatch", slog.String("url", req.BaseUrl),
+		slog.String("version", version), slog.String("expected", req.ProtocolVersion))
+	return nil
+}
+
+// The answers of the repositories to the checks before a harvest, by base
+// URL, asked once for all the requests of the harvest: its windows, its
+// sets or the repositories of a Harvester
+type preflights struct {
+	mu      sync.Mutex
+	answers map[string]*preflight
+}
+
+// The answers of a repository; one request asks for them while the others
+// wait, and a failure leaves them to be asked again
+type preflight struct {
+	mu       sync.Mutex
+	identify *Identify
+	prefixes []string
+}
+
+// The answers of the repository at the base URL
+func (checks *preflights) of(baseUrl string) *preflight {
+	checks.mu.Lock()
+	defer checks.mu.Unlock()
+	if checks.answers == nil {
+		checks.answers = map[string]*preflight{}
+	}
+	answer, ok := checks.answers[baseUrl]
+	if !ok {
+		answer = &preflight{}
+		checks.answers[baseUrl] = answer
+	}
+	return answer
+}
+
+// The Identify response of the repository, asked once per harvest
+// for the checks before it
+func (checks *preflights) identifyRepository(ctx context.Context, req *Request, summary *Summary) (*Identify, error) {
+	answer := checks.of(req.BaseUrl)
+	answer.mu.Lock()
+	defer answer.mu.Unlock()
+	if answer.identify != nil {
+		return answer.identify, nil
+	}
+
+	resp, err := req.withVerb(VerbIdentify).perform(ctx, summary)
+	if err != nil {
+		return nil, err
+	}
+	answer.identify = &resp.Identify
+	return answer.identify, nil
+}
+
+// The answers to the checks before the harvest of the Request: those of
+// the larger harvest it is part of, or new ones
+func (req *Request) sharedPreflights() *preflights {
+	if req.preflights == nil {
+		return &preflights{}
+	}
+	return req.preflights
+}
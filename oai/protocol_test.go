@@ -0,0 +1,60 @@
+package oai
+
+import (
+	"context"
+	"testing"
+)
+
+// The number of requests with the verb the repository has served
+func (repo *testRepository) servedVerb(verb Verb) int {
+	n := 0
+	for _, query := range repo.served() {
+		if query.Get("verb") == string(verb) {
+			n++
+		}
+	}
+	return n
+}
+
+func TestEachHarvestChecksTheRepository(t *testing.T) {
+	repo := newTestRepository(4, 10, 0)
+	server := repo.serve(t)
+	req := testRequest(server)
+	req.ProtocolVersion = ProtocolVersion
+	req.VerifyMetadataPrefix = true
+
+	for i := 0; i < 2; i++ {
+		if _, err := req.HarvestRecordsContext(context.Background(), func(*Record) {}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if identify, formats := repo.servedVerb(VerbIdentify), repo.servedVerb(VerbListMetadataFormats); identify != 2 || formats != 2 {
+		t.Errorf("%d Identify and %d ListMetadataFormats requests for two harvests, want 2 of each", identify, formats)
+	}
+
+	req.MetadataPrefix = "marcxml"
+	if _, err := req.HarvestRecordsContext(context.Background(), func(*Record) {}); err == nil {
+		t.Error("harvesting a metadataPrefix the repository does not offer succeeded")
+	}
+}
+
+func TestHarvesterChecksTheRepositoryOnce(t *testing.T) {
+	repo := newTestRepository(4, 10, 0)
+	server := repo.serve(t)
+	requests := []*Request{}
+	for _, set := range []string{"even", "odd"} {
+		req := testRequest(server)
+		req.Set = set
+		req.ProtocolVersion = ProtocolVersion
+		req.VerifyMetadataPrefix = true
+		requests = append(requests, req)
+	}
+
+	harvester := &Harvester{Concurrency: 2}
+	if _, err := harvester.HarvestAllContext(context.Background(), requests, func(string, *Record) {}); err != nil {
+		t.Fatal(err)
+	}
+	if identify, formats := repo.servedVerb(VerbIdentify), repo.servedVerb(VerbListMetadataFormats); identify != 1 || formats != 1 {
+		t.Errorf("%d Identify and %d ListMetadataFormats requests for one run, want 1 of each", identify, formats)
+	}
+}
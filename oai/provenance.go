@@ -0,0 +1,65 @@
+package oai
+
+import (
+	"encoding/xml"
+	"fmt"
+)
+
+// The namespace of the provenance about container
+const ProvenanceNamespace = "http://www.openarchives.org/OAI/2.0/provenance"
+
+// The provenance about container of a harvested and re-exposed record
+type Provenance struct {
+	XMLName           xml.Name            `xml:"provenance"`
+	OriginDescription []OriginDescription `xml:"originDescription"`
+}
+
+// The origin of a record: the repository it was harvested from, when,
+// and whether its metadata was altered; the nested OriginDescription
+// continues the chain when that repository harvested it in turn
+type OriginDescription struct {
+	HarvestDate       string              `xml:"harvestDate,attr"`
+	Altered           bool                `xml:"altered,attr"`
+	BaseURL           string              `xml:"baseURL"`
+	Identifier        string              `xml:"identifier"`
+	Datestamp         string              `xml:"datestamp"`
+	MetadataNamespace string              `xml:"metadataNamespace"`
+	OriginDescription []OriginDescription `xml:"originDescription"`
+}
+
+// The name of the element contained in the about container
+func (ab About) Name() string {
+	name, _ := rootElement(ab.Body)
+	return name.Local
+}
+
+// Decode the element contained in the about container into v
+func (ab About) Decode(v interface{}) error {
+	return xml.Unmarshal(ab.Body, v)
+}
+
+// Find the first about container holding an element with the given
+// (local) name, such as provenance or rights
+func (record *Record) FindAbout(name string) (About, bool) {
+	for _, about := range record.About {
+		if about.Name() == name {
+			return about, true
+		}
+	}
+	return About{}, false
+}
+
+// The origin descriptions of the provenance about container of the
+// Record, or nil when it has none
+func (record *Record) Provenance() ([]OriginDescription, error) {
+	about, ok := record.FindAbout("provenance")
+	if !ok {
+		return nil, nil
+	}
+
+	provenance := &Provenance{}
+	if err := about.Decode(provenance); err != nil {
+		return nil, fmt.Errorf("oai: decoding provenance of %s: %w", record.Header.Identifier, err)
+	}
+	return provenance.OriginDescription, nil
+}
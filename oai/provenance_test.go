@@ -0,0 +1,68 @@
+package oai
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestRecordWithTwoAbouts(t *testing.T) {
+	record := &decodeFixture(t, "testdata/listrecords.xml").ListRecords.Records[0]
+	if len(record.About) != 2 || record.About[0].Name() != "provenance" || record.About[1].Name() != "rights" {
+		t.Fatalf("%d about containers, want provenance and rights", len(record.About))
+	}
+	if _, ok := record.FindAbout("rights"); !ok {
+		t.Error("rights about container not found")
+	}
+	origins, err := record.Provenance()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(origins) != 1 || origins[0].BaseURL != "http://original.example.org/oai" || origins[0].Altered {
+		t.Errorf("origin descriptions %#v", origins)
+	}
+}
+
+func TestWritersKeepAllAbouts(t *testing.T) {
+	record := &decodeFixture(t, "testdata/listrecords.xml").ListRecords.Records[0]
+
+	dirWriter := &DirWriter{Dir: t.TempDir()}
+	if err := dirWriter.Write(context.Background(), record); err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.Open(dirWriter.Path(record.Header.Identifier))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	written, err := ParseRecord(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(written.About, record.About) {
+		t.Errorf("directory record about containers\n%#v\nwant\n%#v", written.About, record.About)
+	}
+
+	var archive bytes.Buffer
+	tarWriter := NewTarWriter(&archive)
+	if err := tarWriter.Write(context.Background(), record); err != nil {
+		t.Fatal(err)
+	}
+	if err := tarWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+	reader := tar.NewReader(&archive)
+	if _, err := reader.Next(); err != nil {
+		t.Fatal(err)
+	}
+	archived, err := ParseRecord(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(archived.About, record.About) {
+		t.Errorf("archived record about containers\n%#v\nwant\n%#v", archived.About, record.About)
+	}
+}
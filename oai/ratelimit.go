@@ -0,0 +1,121 @@
+package oai
+
+import (
+	"context"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// A token bucket limiting the rate of requests, which may be shared by
+// several Requests and harvests hitting the same host
+type RateLimiter struct {
+	mu      sync.Mutex
+	rate    float64
+	burst   float64
+	tokens  float64
+	updated time.Time
+}
+
+// A RateLimiter allowing perSecond requests per second on average, and
+// bursts of up to burst requests (at least one); with perSecond zero or
+// less, it does not limit at all
+func NewRateLimiter(perSecond float64, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &RateLimiter{rate: perSecond, burst: float64(burst), tokens: float64(burst)}
+}
+
+// Wait until a request may be sent, or until the context is done
+func (limiter *RateLimiter) Wait(ctx context.Context) error {
+	for {
+		delay := limiter.take()
+		if delay == 0 {
+			return nil
+		}
+		if err := sleep(ctx, delay); err != nil {
+			return err
+		}
+	}
+}
+
+// Take a token if there is one, or else tell how long until there is
+func (limiter *RateLimiter) take() time.Duration {
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+	if limiter.rate <= 0 {
+		return 0
+	}
+
+	now := time.Now()
+	if !limiter.updated.IsZero() {
+		limiter.tokens += now.Sub(limiter.updated).Seconds() * limiter.rate
+		if limiter.tokens > limiter.burst {
+			limiter.tokens = limiter.burst
+		}
+	}
+	limiter.updated = now
+
+	if limiter.tokens >= 1 {
+		limiter.tokens--
+		return 0
+	}
+	return time.Duration((1 - limiter.tokens) / limiter.rate * float64(time.Second))
+}
+
+// The rate and burst of the RateLimiter of a host; a PerSecond of zero
+// or less means no limit
+type HostLimit struct {
+	PerSecond float64
+	Burst     int
+}
+
+// A RateLimiter per host, created on first use, for concurrent harvests
+// of several repositories of which some share a host; share it between
+// the Requests, or set it on a Harvester
+type HostRateLimiter struct {
+	// The limit of the hosts without a limit of their own in Hosts;
+	// the zero HostLimit leaves them unlimited
+	Default HostLimit
+	// Stricter or looser limits for particular hosts, by host name
+	// with the port if the URLs have one
+	Hosts map[string]HostLimit
+
+	mu       sync.Mutex
+	limiters map[string]*RateLimiter
+}
+
+// The RateLimiter of the host, created on first use
+func (hostLimiter *HostRateLimiter) Limiter(host string) *RateLimiter {
+	hostLimiter.mu.Lock()
+	defer hostLimiter.mu.Unlock()
+
+	if limiter, ok := hostLimiter.limiters[host]; ok {
+		return limiter
+	}
+	limit, ok := hostLimiter.Hosts[host]
+	if !ok {
+		limit = hostLimiter.Default
+	}
+	if hostLimiter.limiters == nil {
+		hostLimiter.limiters = map[string]*RateLimiter{}
+	}
+	limiter := NewRateLimiter(limit.PerSecond, limit.Burst)
+	hostLimiter.limiters[host] = limiter
+	return limiter
+}
+
+// Wait until a request to the host may be sent, or until the context is done
+func (hostLimiter *HostRateLimiter) Wait(ctx context.Context, host string) error {
+	return hostLimiter.Limiter(host).Wait(ctx)
+}
+
+// The host the Request is sent to
+func (req *Request) host() string {
+	baseURL, err := url.Parse(req.BaseUrl)
+	if err != nil {
+		return ""
+	}
+	return baseURL.Host
+}
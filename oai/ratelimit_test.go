@@ -0,0 +1,47 @@
+package oai
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterWithoutRateDoesNotLimit(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	limiter := NewRateLimiter(0, 0)
+	for i := 0; i < 100; i++ {
+		if err := limiter.Wait(ctx); err != nil {
+			t.Fatalf("request %d: %v", i, err)
+		}
+	}
+}
+
+func TestRateLimiterLimits(t *testing.T) {
+	limiter := NewRateLimiter(1, 1)
+	if delay := limiter.take(); delay != 0 {
+		t.Fatalf("first request delayed %v", delay)
+	}
+	if delay := limiter.take(); delay <= 0 || delay > time.Second {
+		t.Fatalf("second request delayed %v, want up to a second", delay)
+	}
+}
+
+func TestHostRateLimiterZeroDefaultDoesNotLimit(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	hostLimiter := &HostRateLimiter{Hosts: map[string]HostLimit{"slow.example.org": {PerSecond: 1}}}
+	for i := 0; i < 100; i++ {
+		if err := hostLimiter.Wait(ctx, "fast.example.org"); err != nil {
+			t.Fatalf("request %d: %v", i, err)
+		}
+	}
+	if delay := hostLimiter.Limiter("slow.example.org").take(); delay != 0 {
+		t.Fatalf("first request to the limited host delayed %v", delay)
+	}
+	if delay := hostLimiter.Limiter("slow.example.org").take(); delay == 0 {
+		t.Fatal("second request to the limited host not delayed")
+	}
+}
@@ -0,0 +1,37 @@
+package oai
+
+import (
+	"context"
+	"errors"
+)
+
+// Harvest the records of a complete OAI set
+// call the record callback function for each Record, carrying on when it
+// returns an error, and return the errors it returned joined together
+func (req *Request) HarvestRecordsE(callback func(*Record) error) error {
+	_, err := req.HarvestRecordsEContext(context.Background(), callback)
+	return err
+}
+
+// Harvest the records of a complete OAI set
+// call the record callback function for each Record, collecting the errors
+// it returns as *RecordError in the RecordErrors of the Summary instead of
+// stopping, so records that fail to process can be routed aside
+// and return the Summary of the harvest and the error that ended it, or
+// else the record errors joined together, if any
+func (req *Request) HarvestRecordsEContext(ctx context.Context, callback func(*Record) error) (Summary, error) {
+	recordErrors := []error{}
+	summary, err := req.HarvestRecordsContext(ctx, func(record *Record) {
+		if err := callback(record); err != nil {
+			recordErrors = append(recordErrors, &RecordError{Identifier: record.Header.Identifier, Err: err})
+		}
+	})
+	summary.RecordErrors = append(summary.RecordErrors, recordErrors...)
+
+	var harvestError *HarvestError
+	if errors.As(err, &harvestError) {
+		harvestError.Summary.RecordErrors = summary.RecordErrors
+		return summary, err
+	}
+	return summary, errors.Join(recordErrors...)
+}
@@ -0,0 +1,61 @@
+package oai
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+)
+
+// The base URL of a replayed harvest without one of its own
+const replayBaseURL = "http://replay.invalid/oai"
+
+// Run a harvest from fixture files instead of a repository, for offline
+// regression tests: fixtures maps each resumptionToken to the file with
+// the response to it, and the empty token to the file with the response
+// to the initial request. The settings of the Request apply as for a
+// live harvest, but for the arguments, which are not validated, and a
+// missing fixture fails the harvest with a 404 *HTTPError
+// call the record callback function for each Record
+// and return the Summary of the harvest and the error that ended it, if any
+func (req *Request) ReplayHarvest(fixtures map[string]string, callback func(*Record)) (Summary, error) {
+	req = req.Clone()
+	if req.BaseUrl == "" {
+		req.BaseUrl = replayBaseURL
+	}
+	req.Client = &http.Client{Transport: fixtureTransport(fixtures)}
+	req.SkipValidation = true
+	req.ProtocolVersion, req.VerifyMetadataPrefix, req.AdaptGranularity = "", false, false
+	req.Cache, req.Validators, req.RateLimiter, req.HostRateLimiter = nil, nil, nil, nil
+	req.PageDelay, req.MaxRetries, req.Checkpoint = 0, 0, nil
+	return req.HarvestRecordsContext(context.Background(), callback)
+}
+
+// Answers each request with the fixture file for its resumptionToken
+type fixtureTransport map[string]string
+
+func (fixtures fixtureTransport) RoundTrip(httpRequest *http.Request) (*http.Response, error) {
+	resp := &http.Response{
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     http.Header{},
+		Request:    httpRequest,
+	}
+
+	filename, ok := fixtures[httpRequest.URL.Query().Get("resumptionToken")]
+	if !ok {
+		resp.StatusCode, resp.Status = http.StatusNotFound, "404 no fixture"
+		resp.Body = ioutil.NopCloser(bytes.NewReader(nil))
+		return resp, nil
+	}
+	body, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	resp.StatusCode, resp.Status = http.StatusOK, "200 OK"
+	resp.Header.Set("Content-Type", "text/xml")
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	resp.ContentLength = int64(len(body))
+	return resp, nil
+}
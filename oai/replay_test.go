@@ -0,0 +1,45 @@
+package oai
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestReplayHarvest(t *testing.T) {
+	fixtures := map[string]string{
+		"":       "testdata/listrecords.xml",
+		"page-2": "testdata/listrecords-2.xml",
+	}
+	var records []*Record
+	summary, err := NewRequest("", WithMetadataPrefix("oai_dc")).ReplayHarvest(fixtures, func(record *Record) {
+		records = append(records, record)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"oai:repository.example.org:1", "oai:repository.example.org:2", "oai:repository.example.org:3"}
+	if ids := identifiers(records); !reflect.DeepEqual(ids, want) {
+		t.Errorf("replayed %v, want %v", ids, want)
+	}
+	if summary.Requests != 2 || summary.Records != 3 || summary.Deleted != 1 {
+		t.Errorf("summary of %d requests, %d records, %d deleted, want 2, 3, 1", summary.Requests, summary.Records, summary.Deleted)
+	}
+	if !records[1].Header.IsDeleted() || len(records[0].About) != 2 {
+		t.Error("the replayed records differ from the fixtures")
+	}
+}
+
+func TestReplayHarvestMissingFixture(t *testing.T) {
+	fixtures := map[string]string{"": "testdata/listrecords.xml"}
+	summary, err := NewRequest("", WithMetadataPrefix("oai_dc")).ReplayHarvest(fixtures, func(*Record) {})
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusNotFound {
+		t.Fatalf("harvest with a missing fixture ended with %v, want a 404 *HTTPError", err)
+	}
+	if summary.Records != 2 {
+		t.Errorf("%d records before the missing fixture, want 2", summary.Records)
+	}
+}
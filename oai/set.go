@@ -0,0 +1,83 @@
+package oai
+
+import (
+	"context"
+	"strings"
+)
+
+// Whether the setSpec equals spec or lies below it in the set hierarchy,
+// in which the levels of a setSpec are separated by colons: a record in
+// collection:images:maps is a member of collection:images and of collection,
+// but not of col
+func setSpecInSet(setSpec, spec string) bool {
+	return setSpec == spec || strings.HasPrefix(setSpec, spec+":")
+}
+
+// Whether the Header belongs to the set with the given spec, directly or
+// through one of its descendant sets
+func (header *Header) InSet(spec string) bool {
+	for _, setSpec := range header.SetSpec {
+		if setSpecInSet(setSpec, spec) {
+			return true
+		}
+	}
+	return false
+}
+
+// Whether the Record belongs to the set with the given spec, directly or
+// through one of its descendant sets
+func (record *Record) InSet(spec string) bool {
+	return record.Header.InSet(spec)
+}
+
+// The specs of the sets the Record is listed in, without the ancestor
+// sets it belongs to through them
+func (record *Record) Sets() []string {
+	return append([]string(nil), record.Header.SetSpec...)
+}
+
+// Filter the records of HarvestRecords and HarvestIdentifiers by their
+// setSpecs on the client side: deliver only the records in one of the
+// include sets, if any are given, and in none of the exclude sets, both
+// matched like InSet, so a set includes its descendant sets. Records
+// filtered out are counted in the Summary, the others may still be
+// dropped as deleted records by SkipDeleted
+func (req *Request) FilterSets(include, exclude []string) {
+	req.IncludeSets, req.ExcludeSets = include, exclude
+}
+
+// Whether the header passes the IncludeSets and ExcludeSets filter
+func (req *Request) setsAllow(header *Header) bool {
+	for _, spec := range req.ExcludeSets {
+		if header.InSet(spec) {
+			return false
+		}
+	}
+	if len(req.IncludeSets) == 0 {
+		return true
+	}
+	for _, spec := range req.IncludeSets {
+		if header.InSet(spec) {
+			return true
+		}
+	}
+	return false
+}
+
+// List all the sets of the repository, following the resumption tokens
+func (req *Request) listSets(ctx context.Context, summary *Summary) ([]Set, error) {
+	req = req.Clone()
+	req.Verb = VerbListSets
+	req.Set, req.MetadataPrefix, req.Identifier, req.From, req.Until = "", "", "", "", ""
+	req.MaxRecords, req.MaxBatches = 0, 0
+	req.ResumptionToken, req.OnResumptionToken = "", nil
+	req.Tee, req.OnProgress = nil, nil
+	req.Checkpoint = nil
+
+	sets := []Set{}
+	err := req.harvest(ctx, summary, func(resp *Response) error {
+		sets = append(sets, resp.ListSets.Set...)
+		return nil
+	})
+	return sets, err
+}
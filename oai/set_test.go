@@ -0,0 +1,34 @@
+package oai
+
+import "testing"
+
+func TestInSet(t *testing.T) {
+	header := &Header{SetSpec: []string{"collection:images:maps", "physics"}}
+	for _, test := range []struct {
+		spec string
+		want bool
+	}{
+		{"collection:images:maps", true},
+		{"physics", true},
+		{"collection:images", true},
+		{"collection", true},
+		{"col", false},
+		{"collection:image", false},
+		{"collection:images:maps:old", false},
+		{"images", false},
+		{"phys", false},
+		{"", false},
+	} {
+		if got := header.InSet(test.spec); got != test.want {
+			t.Errorf("InSet(%q) = %v, want %v", test.spec, got, test.want)
+		}
+		record := &Record{Header: *header}
+		if got := record.InSet(test.spec); got != test.want {
+			t.Errorf("Record.InSet(%q) = %v, want %v", test.spec, got, test.want)
+		}
+	}
+
+	if (&Header{}).InSet("collection") {
+		t.Error("a header without setSpecs is in a set")
+	}
+}
@@ -0,0 +1,112 @@
+package oai
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+)
+
+// The namespace of the root element of a static repository
+const staticRepositoryNamespace = "http://www.openarchives.org/OAI/2.0/static-repository"
+
+// An OAI static repository: a single XML file with the Identify and
+// ListMetadataFormats responses and all the records of the repository,
+// published by a small provider instead of running an endpoint
+type StaticRepository struct {
+	Identify            Identify
+	ListMetadataFormats ListMetadataFormats
+
+	// The records by metadataPrefix
+	ListRecords map[string][]Record
+}
+
+// Parse the static repository XML read from r
+func LoadStaticRepository(r io.Reader) (*StaticRepository, error) {
+	decoder := newDecoder(r, nil)
+
+	var root xml.StartElement
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, fmt.Errorf("oai: reading static repository: %w", err)
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			root = start
+			break
+		}
+	}
+	if root.Name.Local != "Repository" {
+		return nil, fmt.Errorf("oai: static repository root element %s instead of Repository", root.Name.Local)
+	}
+	if root.Name.Space != staticRepositoryNamespace && root.Name.Space != "" {
+		return nil, fmt.Errorf("oai: static repository in namespace %s", root.Name.Space)
+	}
+
+	repository := &StaticRepository{ListRecords: map[string][]Record{}}
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, fmt.Errorf("oai: reading static repository: %w", err)
+		}
+		if _, ok := token.(xml.EndElement); ok {
+			return repository, nil
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		switch start.Name.Local {
+		case "Identify":
+			err = decoder.DecodeElement(&repository.Identify, &start)
+		case "ListMetadataFormats":
+			err = decoder.DecodeElement(&repository.ListMetadataFormats, &start)
+		case "ListRecords":
+			err = repository.decodeRecords(decoder, root, start)
+		default:
+			err = decoder.Skip()
+		}
+		if err != nil {
+			return nil, fmt.Errorf("oai: reading static repository %s: %w", start.Name.Local, err)
+		}
+	}
+}
+
+// Decode a ListRecords element of the static repository, with the
+// namespace prefixes declared on it and on the root in scope of the metadata
+func (repository *StaticRepository) decodeRecords(decoder *xml.Decoder, root, start xml.StartElement) error {
+	var list struct {
+		MetadataPrefix string   `xml:"metadataPrefix,attr"`
+		Records        []Record `xml:"record"`
+	}
+	if err := decoder.DecodeElement(&list, &start); err != nil {
+		return err
+	}
+
+	namespaces := append(prefixDeclarations(root), prefixDeclarations(start)...)
+	for i := range list.Records {
+		list.Records[i].Metadata.namespaces = namespaces
+	}
+	repository.ListRecords[list.MetadataPrefix] = append(repository.ListRecords[list.MetadataPrefix], list.Records...)
+	return nil
+}
+
+// The metadata prefixes of the records in the static repository
+func (repository *StaticRepository) MetadataPrefixes() []string {
+	prefixes := []string{}
+	for _, format := range repository.ListMetadataFormats.MetadataFormat {
+		if _, ok := repository.ListRecords[format.MetadataPrefix]; ok {
+			prefixes = append(prefixes, format.MetadataPrefix)
+		}
+	}
+	return prefixes
+}
+
+// Iterate the records of the static repository in the given metadata format
+// call the record callback function for each Record
+func (repository *StaticRepository) HarvestRecords(metadataPrefix string, callback func(*Record)) {
+	records := repository.ListRecords[metadataPrefix]
+	for i := range records {
+		callback(&records[i])
+	}
+}
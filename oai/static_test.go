@@ -0,0 +1,74 @@
+package oai
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestLoadStaticRepository(t *testing.T) {
+	file, err := os.Open("testdata/static-repository.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	repository, err := LoadStaticRepository(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if repository.Identify.RepositoryName != "Small Static Repository" || repository.Identify.Granularity != "YYYY-MM-DD" {
+		t.Errorf("Identify %+v", repository.Identify)
+	}
+	// The mods format is listed, but has no records
+	if prefixes := strings.Join(repository.MetadataPrefixes(), " "); prefixes != "oai_dc marc21" {
+		t.Errorf("metadata prefixes %s, want oai_dc marc21", prefixes)
+	}
+
+	for _, test := range []struct {
+		prefix      string
+		identifiers []string
+		metadata    string
+	}{
+		{"oai_dc", []string{"oai:an.oai.org:ma/mini/1", "oai:an.oai.org:ma/mini/2"}, "<dc:title>"},
+		{"marc21", []string{"oai:an.oai.org:ma/mini/1"}, `<subfield code="a">`},
+		{"mods", nil, ""},
+	} {
+		var records []*Record
+		repository.HarvestRecords(test.prefix, func(record *Record) { records = append(records, record) })
+		if ids := identifiers(records); strings.Join(ids, " ") != strings.Join(test.identifiers, " ") {
+			t.Errorf("%s records %v, want %v", test.prefix, ids, test.identifiers)
+		}
+		for _, record := range records {
+			if !strings.Contains(string(record.Metadata.Body), test.metadata) {
+				t.Errorf("%s metadata of %s: %s", test.prefix, record.Header.Identifier, record.Metadata.Body)
+			}
+		}
+	}
+
+	// The prefixes declared on the root and on ListRecords stay in scope
+	// of the metadata
+	var records []*Record
+	repository.HarvestRecords("oai_dc", func(record *Record) { records = append(records, record) })
+	var document strings.Builder
+	if err := records[0].WriteXML(&document); err != nil {
+		t.Fatal(err)
+	}
+	for _, declaration := range []string{`xmlns:dc="http://purl.org/dc/elements/1.1/"`, `xmlns:oai_dc="http://www.openarchives.org/OAI/2.0/oai_dc/"`} {
+		if !strings.Contains(document.String(), declaration) {
+			t.Errorf("%s missing from\n%s", declaration, document.String())
+		}
+	}
+}
+
+func TestLoadStaticRepositoryRejectsOtherDocuments(t *testing.T) {
+	for _, document := range []string{
+		`<OAI-PMH xmlns="http://www.openarchives.org/OAI/2.0/"/>`,
+		`<Repository xmlns="http://example.org/other"/>`,
+		`<Repository xmlns="http://www.openarchives.org/OAI/2.0/static-repository">`,
+	} {
+		if _, err := LoadStaticRepository(strings.NewReader(document)); err == nil {
+			t.Errorf("%s loaded", document)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package oai
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"time"
+)
+
+// A local mirror of the records of a repository by identifier, kept up
+// to date by HarvestInto; the oai/boltstore package has one on disk
+type RecordStore interface {
+	// Store the record, replacing the one with the same identifier
+	Put(record *Record) error
+	// Remove the record with the identifier; removing one not stored is
+	// not an error
+	Delete(identifier string) error
+	// The record with the identifier, and whether it is stored
+	Get(identifier string) (*Record, bool, error)
+	// Call the callback for each record stored with a datestamp at or
+	// after the given one, tombstones included, in the order of their
+	// datestamps, until it returns an error, which is returned
+	IterateSince(datestamp time.Time, callback func(*Record) error) error
+}
+
+// Harvest the records of a complete OAI set into the RecordStore: a record
+// replaces the stored one unless that has a later datestamp, and a deleted
+// record replaces it with a tombstone, its deleted header, so an older
+// version harvested later does not bring it back; return the Summary of
+// the harvest and the error that ended it, a failing store included.
+// Deleted records are applied whatever SkipDeleted and OnDeleted say; the
+// records left out as stale are counted in Summary.Stale
+func (req *Request) HarvestInto(ctx context.Context, store RecordStore) (Summary, error) {
+	req = req.Clone()
+	req.Verb = VerbListRecords
+	req.SkipDeleted, req.OnDeleted = false, nil
+	start, summary := time.Now(), Summary{}
+	err := req.harvestRecords(ctx, &summary, func(record *Record) error {
+		stale, err := storeRecord(store, record)
+		if stale {
+			summary.Stale++
+		}
+		return err
+	})
+	err = summary.finish(start, err)
+	req.logSummary(summary, err)
+	return summary, err
+}
+
+// Apply the record to the store, unless the stored version is newer
+func storeRecord(store RecordStore, record *Record) (stale bool, err error) {
+	identifier := record.Header.Identifier
+	stored, ok, err := store.Get(identifier)
+	if err != nil {
+		return false, fmt.Errorf("oai: store get %s: %w", identifier, err)
+	}
+	if ok && newerDatestamp(stored.Header.DateStamp, record.Header.DateStamp) {
+		return true, nil
+	}
+
+	// A deleted record is stored as well, as the tombstone with the
+	// datestamp of the deletion
+	if err := store.Put(record); err != nil {
+		return false, fmt.Errorf("oai: store %s: %w", identifier, err)
+	}
+	return false, nil
+}
+
+// Whether datestamp a is later than datestamp b; when either cannot be
+// parsed, b is taken to be the newer
+func newerDatestamp(a, b string) bool {
+	aTime, err := ParseDatestamp(a)
+	if err != nil {
+		return false
+	}
+	bTime, err := ParseDatestamp(b)
+	return err == nil && aTime.After(bTime)
+}
+
+// Parse a record element as written by Record.WriteXML, for stores that
+// keep records as XML documents; the namespace prefixes declared on the
+// record element stay in scope of its metadata
+func ParseRecord(r io.Reader) (*Record, error) {
+	decoder := newDecoder(r, nil)
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, fmt.Errorf("oai: reading record: %w", err)
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if start.Name.Local != "record" {
+			return nil, fmt.Errorf("oai: root element %s instead of record", start.Name.Local)
+		}
+
+		record := &Record{}
+		if err := decoder.DecodeElement(record, &start); err != nil {
+			return nil, fmt.Errorf("oai: reading record: %w", err)
+		}
+		if namespaces := prefixDeclarations(start); len(namespaces) > 0 {
+			record.Metadata.namespaces = namespaces
+		}
+		return record, nil
+	}
+}
@@ -0,0 +1,119 @@
+package oai
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// A RecordStore in memory
+type memoryStore struct {
+	mu      sync.Mutex
+	records map[string]*Record
+}
+
+func (store *memoryStore) Put(record *Record) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if store.records == nil {
+		store.records = map[string]*Record{}
+	}
+	store.records[record.Header.Identifier] = record
+	return nil
+}
+
+func (store *memoryStore) Delete(identifier string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	delete(store.records, identifier)
+	return nil
+}
+
+func (store *memoryStore) Get(identifier string) (*Record, bool, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	record, ok := store.records[identifier]
+	return record, ok, nil
+}
+
+func (store *memoryStore) IterateSince(datestamp time.Time, callback func(*Record) error) error {
+	store.mu.Lock()
+	records := []*Record{}
+	for _, record := range store.records {
+		if recordTime, _ := record.Header.Datetime(); !recordTime.Before(datestamp) {
+			records = append(records, record)
+		}
+	}
+	store.mu.Unlock()
+	sort.Slice(records, func(i, j int) bool { return records[i].Header.DateStamp < records[j].Header.DateStamp })
+	for _, record := range records {
+		if err := callback(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestHarvestIntoKeepsTombstones(t *testing.T) {
+	repo := newTestRepository(4, 10, 0)
+	server := repo.serve(t)
+	store := &memoryStore{}
+	if _, err := testRequest(server).HarvestInto(context.Background(), store); err != nil {
+		t.Fatal(err)
+	}
+
+	// The repository deletes oai:test:1, later a mirror of it replays the
+	// old version
+	old := repo.records[1]
+	repo.records[1].deleted = true
+	repo.records[1].datestamp = "2024-02-01T00:00:00Z"
+	if _, err := testRequest(server).HarvestInto(context.Background(), store); err != nil {
+		t.Fatal(err)
+	}
+	repo.records[1] = old
+	summary, err := testRequest(server).HarvestInto(context.Background(), store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Stale != 1 {
+		t.Errorf("%d stale records, want the replayed one", summary.Stale)
+	}
+	record, ok, _ := store.Get("oai:test:1")
+	if !ok || !record.Header.IsDeleted() || record.Header.DateStamp != "2024-02-01T00:00:00Z" {
+		t.Errorf("stored %+v, want the tombstone", record)
+	}
+}
+
+func TestParseRecordReadsWriteXML(t *testing.T) {
+	for _, record := range []Record{
+		{
+			Header: Header{Identifier: "oai:a", DateStamp: "2024-01-01T00:00:00Z", SetSpec: []string{"x"}},
+			Metadata: Metadata{Body: []byte(`<oai_dc:dc xmlns:oai_dc="http://www.openarchives.org/OAI/2.0/oai_dc/" ` +
+				`xmlns:dc="http://purl.org/dc/elements/1.1/"><dc:title>A</dc:title></oai_dc:dc>`)},
+		},
+		{Header: Header{Identifier: "oai:b", DateStamp: "2024-01-02", Status: "deleted"}},
+	} {
+		var document strings.Builder
+		if err := record.WriteXML(&document); err != nil {
+			t.Fatal(err)
+		}
+		parsed, err := ParseRecord(strings.NewReader(document.String()))
+		if err != nil {
+			t.Fatalf("%s: %v", document.String(), err)
+		}
+		if parsed.Header.Identifier != record.Header.Identifier || parsed.Header.DateStamp != record.Header.DateStamp ||
+			parsed.Header.IsDeleted() != record.Header.IsDeleted() || len(parsed.Header.SetSpec) != len(record.Header.SetSpec) {
+			t.Errorf("parsed header %+v, want %+v", parsed.Header, record.Header)
+		}
+		if !strings.Contains(string(parsed.Metadata.Body), "<dc:title>A</dc:title>") != (len(record.Metadata.Body) == 0) {
+			t.Errorf("parsed metadata %q of %s", parsed.Metadata.Body, record.Header.Identifier)
+		}
+	}
+
+	if _, err := ParseRecord(strings.NewReader("<header/>")); err == nil {
+		t.Error("document without a record element parsed")
+	}
+}
@@ -0,0 +1,192 @@
+package oai
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// How a harvest ended
+type Outcome int
+
+const (
+	// The harvest ran until the repository had no more pages to offer,
+	// or until a configured limit was reached
+	OutcomeCompleted Outcome = iota
+	// The harvest was stopped by an error
+	OutcomeAborted
+	// The harvest was stopped by the deadline of its context
+	OutcomeDeadline
+)
+
+// The statistics of a harvest
+type Summary struct {
+	// The number of HTTP requests made, retries included
+	Requests int
+	// The number of HTTP requests made by host
+	HostRequests map[string]int
+	// The number of requests that were retries of a failed request
+	Retries int
+	// The number of bytes downloaded, and their number once decompressed
+	// when the repository compressed its responses
+	Bytes             int64
+	UncompressedBytes int64
+
+	// The number of records (or headers) delivered to the callback, and
+	// the number of deleted records harvested, whether SkipDeleted or
+	// OnDeleted kept them from the callback or not
+	Records, Deleted int
+
+	// The number of duplicate records dropped by Dedupe, the number of
+	// records left out by FilterSets and the number rejected by RecordFilter
+	Duplicates, Filtered, Rejected int
+
+	// The number of records HarvestInto left out as the RecordStore
+	// held a newer version
+	Stale int
+
+	// The time spent waiting PageDelay between the pages
+	Slept time.Duration
+
+	// The earliest and the latest datestamp of the harvested headers
+	FirstDatestamp, LastDatestamp time.Time
+
+	// The panics recovered from the OnRequest and OnResponse hooks
+	HookErrors []error
+
+	// The errors returned by the record callback of HarvestRecordsE,
+	// each a *RecordError
+	RecordErrors []error
+
+	// Whether MaxRecords or MaxBatches stopped the harvest before all the
+	// records were delivered, and the resumptionToken to continue with
+	// (see Request.ResumeFrom). When MaxRecords cut a page short, that is
+	// the token of the page itself, empty for the first page, so the
+	// records of the page delivered already are delivered again when
+	// continuing: none is lost, and Dedupe can drop the repeats
+	Truncated       bool
+	ResumptionToken string
+
+	// The wall-clock duration of the harvest and how it ended
+	Duration time.Duration
+	Outcome  Outcome
+}
+
+// The error ending a harvest, carrying the Summary of what was
+// harvested up to that point
+type HarvestError struct {
+	Summary Summary
+	Err     error
+}
+
+func (harvestError *HarvestError) Error() string { return harvestError.Err.Error() }
+
+func (harvestError *HarvestError) Unwrap() error { return harvestError.Err }
+
+// The error returned by a record callback for the record with the
+// given identifier
+type RecordError struct {
+	Identifier string
+	Err        error
+}
+
+func (recordError *RecordError) Error() string {
+	return "oai: record " + recordError.Identifier + ": " + recordError.Err.Error()
+}
+
+func (recordError *RecordError) Unwrap() error { return recordError.Err }
+
+// Account for an HTTP request to the host
+func (summary *Summary) addRequest(host string) {
+	summary.Requests++
+	if summary.HostRequests == nil {
+		summary.HostRequests = map[string]int{}
+	}
+	summary.HostRequests[host]++
+}
+
+// The average number of requests per second sent to each host during
+// the harvest, the effective rate to tune a HostRateLimiter with
+func (summary *Summary) HostRates() map[string]float64 {
+	rates := map[string]float64{}
+	if summary.Duration <= 0 {
+		return rates
+	}
+	for host, requests := range summary.HostRequests {
+		rates[host] = float64(requests) / summary.Duration.Seconds()
+	}
+	return rates
+}
+
+// Account for a delivered record or header
+func (summary *Summary) addHeader(header *Header) {
+	summary.Records++
+	summary.addHarvested(header)
+}
+
+// Account for a harvested record or header, delivered or not
+func (summary *Summary) addHarvested(header *Header) {
+	if header.IsDeleted() {
+		summary.Deleted++
+	}
+
+	datestamp, err := header.Datetime()
+	if err != nil {
+		return
+	}
+	if summary.FirstDatestamp.IsZero() || datestamp.Before(summary.FirstDatestamp) {
+		summary.FirstDatestamp = datestamp
+	}
+	if datestamp.After(summary.LastDatestamp) {
+		summary.LastDatestamp = datestamp
+	}
+}
+
+// Add the counts of another harvest, part of this one, to the Summary
+func (summary *Summary) merge(other Summary) {
+	summary.Requests += other.Requests
+	summary.Retries += other.Retries
+	for host, requests := range other.HostRequests {
+		if summary.HostRequests == nil {
+			summary.HostRequests = map[string]int{}
+		}
+		summary.HostRequests[host] += requests
+	}
+	summary.Bytes += other.Bytes
+	summary.UncompressedBytes += other.UncompressedBytes
+	summary.Records += other.Records
+	summary.Deleted += other.Deleted
+	summary.Duplicates += other.Duplicates
+	summary.Filtered += other.Filtered
+	summary.Rejected += other.Rejected
+	summary.Stale += other.Stale
+	summary.Slept += other.Slept
+	summary.Truncated = summary.Truncated || other.Truncated
+	summary.HookErrors = append(summary.HookErrors, other.HookErrors...)
+	summary.RecordErrors = append(summary.RecordErrors, other.RecordErrors...)
+
+	if !other.FirstDatestamp.IsZero() &&
+		(summary.FirstDatestamp.IsZero() || other.FirstDatestamp.Before(summary.FirstDatestamp)) {
+		summary.FirstDatestamp = other.FirstDatestamp
+	}
+	if other.LastDatestamp.After(summary.LastDatestamp) {
+		summary.LastDatestamp = other.LastDatestamp
+	}
+}
+
+// Complete the Summary of the harvest started at start and ended by err,
+// returning err wrapped in a HarvestError
+func (summary *Summary) finish(start time.Time, err error) error {
+	summary.Duration = time.Since(start)
+
+	switch {
+	case err == nil:
+		summary.Outcome = OutcomeCompleted
+		return nil
+	case errors.Is(err, context.DeadlineExceeded):
+		summary.Outcome = OutcomeDeadline
+	default:
+		summary.Outcome = OutcomeAborted
+	}
+	return &HarvestError{Summary: *summary, Err: err}
+}
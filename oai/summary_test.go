@@ -0,0 +1,42 @@
+package oai
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSkippedDeletedRecordsAreNotDelivered(t *testing.T) {
+	server := newTestRepository(9, 4, 3).serve(t)
+
+	req := testRequest(server)
+	req.SkipDeleted = true
+	delivered := 0
+	summary, err := req.HarvestRecordsContext(context.Background(), func(*Record) { delivered++ })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if delivered != 6 || summary.Records != 6 || summary.Deleted != 3 {
+		t.Errorf("SkipDeleted: %d delivered, summary %d records and %d deleted, want 6, 6 and 3",
+			delivered, summary.Records, summary.Deleted)
+	}
+
+	req = testRequest(server)
+	tombstones := 0
+	req.OnDeleted = func(*Header) { tombstones++ }
+	summary, err = req.HarvestRecordsContext(context.Background(), func(*Record) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tombstones != 3 || summary.Records != 6 || summary.Deleted != 3 {
+		t.Errorf("OnDeleted: %d tombstones, summary %d records and %d deleted, want 3, 6 and 3",
+			tombstones, summary.Records, summary.Deleted)
+	}
+
+	summary, err = testRequest(server).HarvestRecordsContext(context.Background(), func(*Record) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Records != 9 || summary.Deleted != 3 {
+		t.Errorf("default: summary %d records and %d deleted, want 9 and 3", summary.Records, summary.Deleted)
+	}
+}
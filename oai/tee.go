@@ -0,0 +1,54 @@
+package oai
+
+import (
+	"context"
+	"io"
+)
+
+// Harvest a complete OAI set
+// write the raw XML of each page to w
+func (req *Request) HarvestToWriter(w io.Writer) {
+	if _, err := req.HarvestToWriterContext(context.Background(), w); err != nil {
+		panic(err)
+	}
+}
+
+// Harvest a complete OAI set
+// write the raw XML of each page to w verbatim, one response after the
+// other, for a reproducible snapshot of what the repository returned;
+// wrap w in a gzip.Writer for a compressed archive, and read it back
+// with ReadResponses
+// and return the Summary of the harvest and the error that ended it, if any
+func (req *Request) HarvestToWriterContext(ctx context.Context, w io.Writer) (Summary, error) {
+	req = req.Clone()
+	req.Tee = w
+	return req.HarvestContext(ctx, func(*Response) {})
+}
+
+// Reads the first OAI PMH response from r
+func FromReader(r io.Reader) (*Response, error) {
+	oaiResponse, err := decodeNext(newDecoder(r, nil))
+	if err == io.EOF {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return oaiResponse, err
+}
+
+// Read the OAI PMH responses written one after the other to r, as by
+// HarvestToWriter or Tee
+// call the callback function with each Response until it returns an error
+func ReadResponses(r io.Reader, callback func(*Response) error) error {
+	decoder := newDecoder(r, nil)
+	for {
+		oaiResponse, err := decodeNext(decoder)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := callback(oaiResponse); err != nil {
+			return err
+		}
+	}
+}
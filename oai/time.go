@@ -0,0 +1,161 @@
+package oai
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// The finest datestamp granularity a repository supports, as advertised
+// in the granularity element of its Identify response
+type Granularity string
+
+const (
+	GranularityDay    Granularity = "YYYY-MM-DD"
+	GranularitySecond Granularity = "YYYY-MM-DDThh:mm:ssZ"
+)
+
+const (
+	dayLayout    = "2006-01-02"
+	secondLayout = "2006-01-02T15:04:05Z"
+)
+
+// The time layout for datestamps of this granularity,
+// seconds granularity unless the granularity is day
+func (granularity Granularity) layout() string {
+	if granularity == GranularityDay {
+		return dayLayout
+	}
+	return secondLayout
+}
+
+// Format the time as an OAI-PMH datestamp in UTC,
+// truncated to the date for day granularity
+func (granularity Granularity) Format(t time.Time) string {
+	return t.UTC().Format(granularity.layout())
+}
+
+// The granularity of a datestamp argument such as from or until
+func datestampGranularity(datestamp string) Granularity {
+	if len(datestamp) == len(dayLayout) {
+		return GranularityDay
+	}
+	return GranularitySecond
+}
+
+// Set the from argument to the given time, formatted for the
+// granularity of the Request
+func (req *Request) SetFrom(t time.Time) {
+	req.From = req.Granularity.Format(t)
+}
+
+// Set the until argument to the given time, formatted for the
+// granularity of the Request
+func (req *Request) SetUntil(t time.Time) {
+	req.Until = req.Granularity.Format(t)
+}
+
+// Set the granularity of the Request to the one advertised by the
+// repository in its Identify response, asked with the settings of the
+// Request, such as its client, limiters and retries
+func (req *Request) DetectGranularity(ctx context.Context) error {
+	resp, err := req.withVerb(VerbIdentify).PerformContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	switch granularity := Granularity(resp.Identify.Granularity); granularity {
+	case GranularityDay, GranularitySecond:
+		req.Granularity = granularity
+		return nil
+	default:
+		return fmt.Errorf("oai: unknown granularity %q", resp.Identify.Granularity)
+	}
+}
+
+// The layouts of the datetimes found in the wild, the last ones without
+// a time zone, which is then taken to be UTC
+var datetimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999",
+}
+
+// Adapt the from and until arguments to the granularity the repository
+// advertises in its Identify response: datetimes sent to a repository of
+// day granularity are truncated to their date with a warning, or refused
+// if StrictGranularity is set
+func (req *Request) checkGranularity(ctx context.Context, summary *Summary, checks *preflights) error {
+	if !req.AdaptGranularity || req.ResumptionToken != "" || (req.From == "" && req.Until == "") {
+		return nil
+	}
+
+	identify, err := checks.identifyRepository(ctx, req, summary)
+	if err != nil {
+		return err
+	}
+	if Granularity(strings.TrimSpace(identify.Granularity)) != GranularityDay {
+		return nil
+	}
+
+	req.Granularity = GranularityDay
+	for _, datestamp := range []*string{&req.From, &req.Until} {
+		if *datestamp == "" || datestampGranularity(*datestamp) == GranularityDay {
+			continue
+		}
+		if req.StrictGranularity {
+			return fmt.Errorf("oai: %s only supports day granularity, not %q", req.BaseUrl, *datestamp)
+		}
+		t, err := ParseDatestamp(*datestamp)
+		if err != nil {
+			return err
+		}
+		req.logger().Warn("oai datestamp truncated to day granularity",
+			slog.String("datestamp", *datestamp), slog.String("url", req.BaseUrl))
+		*datestamp = GranularityDay.Format(t)
+	}
+	return nil
+}
+
+// Parse an OAI-PMH datestamp of day or seconds granularity,
+// tolerating the fractional seconds, minute precision or missing
+// time zone some repositories emit
+func ParseDatestamp(datestamp string) (time.Time, error) {
+	datestamp = strings.TrimSpace(datestamp)
+	if datestampGranularity(datestamp) == GranularityDay {
+		if t, err := time.Parse(dayLayout, datestamp); err == nil {
+			return t, nil
+		}
+	} else {
+		for _, layout := range datetimeLayouts {
+			if t, err := time.Parse(layout, datestamp); err == nil {
+				return t.UTC(), nil
+			}
+		}
+	}
+	return time.Time{}, fmt.Errorf("oai: invalid datestamp %q", datestamp)
+}
+
+// The datestamp of the Header as a UTC time, whichever of the accepted
+// forms the repository uses
+func (header *Header) Datetime() (time.Time, error) {
+	return ParseDatestamp(header.DateStamp)
+}
+
+// The earliest datestamp of the repository as a UTC time
+func (identify *Identify) EarliestDatetime() (time.Time, error) {
+	return ParseDatestamp(identify.EarliestDatestamp)
+}
+
+// The responseDate of the Response as a UTC time, which the repository
+// always reports with seconds granularity; comparing it with the local
+// clock reveals the skew to account for when setting From for the next run
+func (resp *Response) ResponseTime() (time.Time, error) {
+	if datestampGranularity(strings.TrimSpace(resp.ResponseDate)) != GranularitySecond {
+		return time.Time{}, fmt.Errorf("oai: invalid responseDate %q", resp.ResponseDate)
+	}
+	return ParseDatestamp(resp.ResponseDate)
+}
@@ -0,0 +1,40 @@
+package oai
+
+import (
+	"context"
+	"net/url"
+	"testing"
+)
+
+func TestPreflightsUseTheRequestSettings(t *testing.T) {
+	repo := newTestRepository(1, 10, 0)
+	server := repo.serve(t)
+	req := testRequest(server)
+	req.Sign = func(params url.Values) url.Values {
+		params.Set("signature", "signed")
+		return params
+	}
+
+	if err := req.DetectGranularity(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if req.Granularity != GranularitySecond {
+		t.Errorf("granularity %q, want %q", req.Granularity, GranularitySecond)
+	}
+	if err := req.DetectCompression(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := req.DiscoverRepositories(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+
+	served := repo.served()
+	if len(served) != 3 {
+		t.Fatalf("%d requests, want 3", len(served))
+	}
+	for _, query := range served {
+		if query.Get("verb") != string(VerbIdentify) || query.Get("signature") != "signed" {
+			t.Errorf("preflight %v is not a signed Identify request", query)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package oai
+
+import (
+	"fmt"
+	"strings"
+)
+
+// An OAI-PMH verb; a string type, so literals like "ListRecords" still
+// work, but the constants guard against typos
+type Verb string
+
+const (
+	VerbIdentify            Verb = "Identify"
+	VerbListMetadataFormats Verb = "ListMetadataFormats"
+	VerbListSets            Verb = "ListSets"
+	VerbGetRecord           Verb = "GetRecord"
+	VerbListIdentifiers     Verb = "ListIdentifiers"
+	VerbListRecords         Verb = "ListRecords"
+)
+
+// Whether the verb is one of the six defined by OAI-PMH
+func (verb Verb) Valid() bool {
+	_, ok := verbArgumentMatrix[verb]
+	return ok
+}
+
+// The arguments a verb accepts, per section 4 of the OAI-PMH specification
+type verbArguments struct {
+	required, optional []string
+	// Whether the verb accepts the exclusive resumptionToken argument
+	resumable bool
+}
+
+var verbArgumentMatrix = map[Verb]verbArguments{
+	VerbIdentify:            {},
+	VerbListMetadataFormats: {optional: []string{"identifier"}},
+	VerbListSets:            {resumable: true},
+	VerbGetRecord:           {required: []string{"identifier", "metadataPrefix"}},
+	VerbListIdentifiers: {
+		required:  []string{"metadataPrefix"},
+		optional:  []string{"from", "until", "set"},
+		resumable: true,
+	},
+	VerbListRecords: {
+		required:  []string{"metadataPrefix"},
+		optional:  []string{"from", "until", "set"},
+		resumable: true,
+	},
+}
+
+// The arguments of the Request by their OAI-PMH name, excluding the verb
+func (req *Request) arguments() map[string]string {
+	return map[string]string{
+		"set":             req.Set,
+		"metadataPrefix":  req.MetadataPrefix,
+		"identifier":      req.Identifier,
+		"resumptionToken": req.ResumptionToken,
+		"from":            req.From,
+		"until":           req.Until,
+	}
+}
+
+// Validate checks the Request against the OAI-PMH argument rules of its verb
+// and returns an error naming the offending argument combination, so an
+// illegal request is caught before it is sent to the repository
+func (req *Request) Validate() error {
+	matrix, ok := verbArgumentMatrix[req.Verb]
+	if !ok {
+		if req.Verb == "" {
+			return fmt.Errorf("oai: missing verb")
+		}
+		return fmt.Errorf("oai: illegal verb %q", req.Verb)
+	}
+
+	args := req.arguments()
+	given := []string{}
+	for _, name := range []string{"identifier", "metadataPrefix", "set", "from", "until", "resumptionToken"} {
+		if args[name] != "" {
+			given = append(given, name)
+		}
+	}
+
+	// The resumptionToken is an exclusive argument, except for the
+	// metadataPrefix in ResumptionPermissive mode
+	if args["resumptionToken"] != "" {
+		if req.ResumptionMode == ResumptionPermissive {
+			exclusive := []string{}
+			for _, name := range given {
+				if name != "metadataPrefix" {
+					exclusive = append(exclusive, name)
+				}
+			}
+			given = exclusive
+		}
+		if !matrix.resumable {
+			return fmt.Errorf("oai: %s does not allow the resumptionToken argument", req.Verb)
+		}
+		if len(given) > 1 {
+			return fmt.Errorf("oai: %s does not allow resumptionToken combined with %s",
+				req.Verb, strings.Join(given[:len(given)-1], ", "))
+		}
+		return nil
+	}
+
+	for _, name := range matrix.required {
+		if args[name] == "" {
+			return fmt.Errorf("oai: %s requires the %s argument", req.Verb, name)
+		}
+	}
+
+	for _, name := range given {
+		if !contains(matrix.required, name) && !contains(matrix.optional, name) {
+			return fmt.Errorf("oai: %s does not allow the %s argument", req.Verb, name)
+		}
+	}
+
+	// Many repositories refuse from and until of different granularities
+	if req.From != "" && req.Until != "" &&
+		datestampGranularity(req.From) != datestampGranularity(req.Until) {
+		return fmt.Errorf("oai: %s does not allow from %q and until %q of different granularities",
+			req.Verb, req.From, req.Until)
+	}
+
+	return nil
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
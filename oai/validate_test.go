@@ -0,0 +1,46 @@
+package oai
+
+import "testing"
+
+func TestValidate(t *testing.T) {
+	for _, test := range []struct {
+		name  string
+		req   Request
+		valid bool
+	}{
+		{"no verb", Request{}, false},
+		{"an unknown verb", Request{Verb: "ListEverything"}, false},
+		{"Identify", Request{Verb: VerbIdentify}, true},
+		{"Identify with a set", Request{Verb: VerbIdentify, Set: "a"}, false},
+		{"ListMetadataFormats", Request{Verb: VerbListMetadataFormats}, true},
+		{"ListMetadataFormats of a record", Request{Verb: VerbListMetadataFormats, Identifier: "oai:a"}, true},
+		{"ListMetadataFormats with a metadataPrefix", Request{Verb: VerbListMetadataFormats, MetadataPrefix: "oai_dc"}, false},
+		{"ListSets", Request{Verb: VerbListSets}, true},
+		{"ListSets resumed", Request{Verb: VerbListSets, ResumptionToken: "t"}, true},
+		{"ListSets with a from", Request{Verb: VerbListSets, From: "2024-01-01"}, false},
+		{"GetRecord", Request{Verb: VerbGetRecord, Identifier: "oai:a", MetadataPrefix: "oai_dc"}, true},
+		{"GetRecord without an identifier", Request{Verb: VerbGetRecord, MetadataPrefix: "oai_dc"}, false},
+		{"GetRecord without a metadataPrefix", Request{Verb: VerbGetRecord, Identifier: "oai:a"}, false},
+		{"GetRecord with a set", Request{Verb: VerbGetRecord, Identifier: "oai:a", MetadataPrefix: "oai_dc", Set: "a"}, false},
+		{"GetRecord resumed", Request{Verb: VerbGetRecord, ResumptionToken: "t"}, false},
+		{"ListRecords", Request{Verb: VerbListRecords, MetadataPrefix: "oai_dc"}, true},
+		{"ListRecords without a metadataPrefix", Request{Verb: VerbListRecords}, false},
+		{"ListRecords of a set and range", Request{Verb: VerbListRecords, MetadataPrefix: "oai_dc", Set: "a",
+			From: "2024-01-01", Until: "2024-12-31"}, true},
+		{"ListRecords of a record", Request{Verb: VerbListRecords, MetadataPrefix: "oai_dc", Identifier: "oai:a"}, false},
+		{"ListRecords from and until of different granularities", Request{Verb: VerbListRecords, MetadataPrefix: "oai_dc",
+			From: "2024-01-01", Until: "2024-12-31T00:00:00Z"}, false},
+		{"ListIdentifiers resumed", Request{Verb: VerbListIdentifiers, ResumptionToken: "t"}, true},
+		{"ListIdentifiers resumed with a set", Request{Verb: VerbListIdentifiers, ResumptionToken: "t", Set: "a"}, false},
+		{"ListRecords resumed with a metadataPrefix", Request{Verb: VerbListRecords, ResumptionToken: "t",
+			MetadataPrefix: "oai_dc"}, false},
+		{"ListRecords resumed permissively with a metadataPrefix", Request{Verb: VerbListRecords, ResumptionToken: "t",
+			MetadataPrefix: "oai_dc", ResumptionMode: ResumptionPermissive}, true},
+		{"ListRecords resumed permissively with a set", Request{Verb: VerbListRecords, ResumptionToken: "t",
+			MetadataPrefix: "oai_dc", Set: "a", ResumptionMode: ResumptionPermissive}, false},
+	} {
+		if err := test.req.Validate(); (err == nil) != test.valid {
+			t.Errorf("%s: Validate() = %v, want valid %v", test.name, err, test.valid)
+		}
+	}
+}
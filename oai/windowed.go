@@ -0,0 +1,156 @@
+package oai
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Harvest the records between from and until in successive date windows
+// of the given length, one complete harvest per window
+// call the record callback function for each Record
+func (req *Request) HarvestWindowed(from, until time.Time, window time.Duration, callback func(*Record)) {
+	if _, err := req.HarvestWindowedContext(context.Background(), from, until, window, callback); err != nil {
+		panic(err)
+	}
+}
+
+// Harvest the records between from and until in successive date windows
+// of the given length, one complete harvest per window, which keeps
+// providers that cannot stream years of data at once from timing out;
+// the window must be a multiple of the granularity of the Request and of
+// the repository, which is asked for it in advance, and
+// windows without records are simply skipped. WindowWorkers windows are
+// harvested at the same time, in order of their start, so the records
+// are only delivered in chronological order by a single worker.
+// MaxRecords applies to all the windows together, MaxBatches and the
+// resumption token of the Request do not apply
+// call the record callback function for each Record, one call at a time
+// and return the Summary of all windows and the error that ended it, if any
+func (req *Request) HarvestWindowedContext(ctx context.Context, from, until time.Time, window time.Duration, callback func(*Record)) (Summary, error) {
+	start, summary := time.Now(), Summary{}
+
+	// Deduplicate and check the repository across all the windows rather
+	// than per window
+	req = req.Clone()
+	req.Verb = VerbListRecords
+	req.preflights = req.sharedPreflights()
+	if err := req.initDedupe(); err != nil {
+		return summary, summary.finish(start, err)
+	}
+
+	// A repository of day granularity cannot harvest windows of hours,
+	// whatever the granularity of the Request
+	if req.Granularity != GranularityDay && req.DryRun == nil {
+		identify, err := req.preflights.identifyRepository(ctx, req, &summary)
+		if err != nil {
+			return summary, summary.finish(start, err)
+		}
+		if Granularity(strings.TrimSpace(identify.Granularity)) == GranularityDay {
+			req.Granularity = GranularityDay
+		}
+	}
+
+	// The bounds are inclusive, so a window ends one granule before
+	// the next one starts
+	granule := time.Second
+	if req.Granularity == GranularityDay {
+		granule = 24 * time.Hour
+	}
+	if window < granule || window%granule != 0 {
+		return summary, summary.finish(start, errors.New("oai: window not a multiple of the granularity"))
+	}
+	from = from.UTC().Truncate(granule)
+
+	harvestCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type dateWindow struct{ from, until time.Time }
+	windows := make(chan dateWindow)
+	go func() {
+		defer close(windows)
+		for windowStart := from; !windowStart.After(until); windowStart = windowStart.Add(window) {
+			windowEnd := windowStart.Add(window - granule)
+			if windowEnd.After(until) {
+				windowEnd = until
+			}
+			select {
+			case windows <- dateWindow{windowStart, windowEnd}:
+			case <-harvestCtx.Done():
+				return
+			}
+		}
+	}()
+
+	workers := req.WindowWorkers
+	if workers < 1 {
+		workers = 1
+	}
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		windowErr error
+		limited   bool
+		delivered Summary
+	)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for dates := range windows {
+				if harvestCtx.Err() != nil {
+					continue
+				}
+				windowReq := req.Clone()
+				windowReq.SetFrom(dates.from)
+				windowReq.SetUntil(dates.until)
+				windowReq.MaxRecords, windowReq.MaxBatches = 0, 0
+				windowReq.ResumptionToken, windowReq.OnResumptionToken = "", nil
+				windowReq.SkipDeleted, windowReq.OnDeleted = false, nil
+				windowReq.Checkpoint = nil
+				windowStart, windowSummary := time.Now(), Summary{}
+				err := windowReq.harvestRecords(harvestCtx, &windowSummary, func(record *Record) error {
+					mu.Lock()
+					defer mu.Unlock()
+					if limited {
+						return nil
+					}
+					req.deliver(&delivered, record, callback)
+					if req.MaxRecords > 0 && delivered.Records >= req.MaxRecords {
+						limited = true
+						cancel()
+					}
+					return nil
+				})
+				err = windowSummary.finish(windowStart, err)
+
+				mu.Lock()
+				summary.merge(windowSummary)
+				if req.OnWindow != nil {
+					req.OnWindow(dates.from, dates.until, windowSummary)
+				}
+				if err != nil && windowErr == nil && !limited {
+					windowErr = errors.Unwrap(err)
+					cancel()
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if windowErr == nil {
+		windowErr = ctx.Err()
+	}
+
+	// Count the records delivered, not those harvested after MaxRecords
+	summary.Records, summary.Deleted = delivered.Records, delivered.Deleted
+	summary.FirstDatestamp, summary.LastDatestamp = delivered.FirstDatestamp, delivered.LastDatestamp
+	summary.Truncated = summary.Truncated || limited
+	err := summary.finish(start, windowErr)
+	req.logSummary(summary, err)
+	return summary, err
+}
@@ -0,0 +1,85 @@
+package oai
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestHarvestWindowedFollowsTheRepositoryGranularity(t *testing.T) {
+	repo := newTestRepository(30, 10, 0)
+	repo.granularity = GranularityDay
+	server := repo.serve(t)
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	until := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	// The Request is of seconds, the repository is not
+	_, err := testRequest(server).HarvestWindowedContext(context.Background(), from, until, time.Hour, func(*Record) {})
+	if err == nil {
+		t.Fatal("windows of an hour accepted by a repository of day granularity")
+	}
+
+	delivered := 0
+	summary, err := testRequest(server).HarvestWindowedContext(context.Background(), from, until, 24*time.Hour,
+		func(*Record) { delivered++ })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if delivered != 30 || summary.Records != 30 {
+		t.Errorf("delivered %d records, summary of %d, want 30", delivered, summary.Records)
+	}
+	for _, query := range repo.served() {
+		if from := query.Get("from"); from != "" && datestampGranularity(from) != GranularityDay {
+			t.Errorf("requested from %q of a repository of day granularity", from)
+		}
+	}
+}
+
+func TestHarvestWindowedInParallel(t *testing.T) {
+	repo := newTestRepository(48, 5, 4)
+	server := repo.serve(t)
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	until := from.Add(47 * time.Hour)
+
+	req := testRequest(server)
+	req.WindowWorkers = 4
+	req.MaxBatches = 1
+	req.ResumptionToken = "5///"
+	req.OnResumptionToken = func(token ResumptionToken) { t.Errorf("window resumption token %q reported", token.Token) }
+	windows := 0
+	req.OnWindow = func(from, until time.Time, summary Summary) { windows++ }
+
+	seen := map[string]bool{}
+	summary, err := req.HarvestWindowedContext(context.Background(), from, until, 6*time.Hour, func(record *Record) {
+		if seen[record.Header.Identifier] {
+			t.Errorf("%s delivered twice", record.Header.Identifier)
+		}
+		seen[record.Header.Identifier] = true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(seen) != 48 || summary.Records != 48 || summary.Deleted != 12 || windows != 8 {
+		t.Errorf("delivered %d records in %d windows, summary of %d with %d deleted, want 48 in 8 with 12 deleted",
+			len(seen), windows, summary.Records, summary.Deleted)
+	}
+}
+
+func TestHarvestWindowedMaxRecordsAcrossWindows(t *testing.T) {
+	server := newTestRepository(48, 5, 0).serve(t)
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	req := testRequest(server)
+	req.WindowWorkers = 4
+	req.MaxRecords = 10
+	delivered := 0
+	summary, err := req.HarvestWindowedContext(context.Background(), from, from.Add(47*time.Hour), 6*time.Hour,
+		func(*Record) { delivered++ })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if delivered != 10 || summary.Records != 10 || !summary.Truncated {
+		t.Errorf("delivered %d records, summary of %d, truncated %v, want 10 and truncated",
+			delivered, summary.Records, summary.Truncated)
+	}
+}
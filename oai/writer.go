@@ -0,0 +1,137 @@
+package oai
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// A destination for harvested records, such as a directory or an archive
+type RecordWriter interface {
+	Write(ctx context.Context, record *Record) error
+	Close() error
+}
+
+// Harvest the records of a complete OAI set into the RecordWriter, which
+// is not closed, and return the Summary of the harvest and the error that
+// ended it, a failing Write included
+func (req *Request) HarvestTo(ctx context.Context, writer RecordWriter) (Summary, error) {
+	req = req.Clone()
+	req.Verb = VerbListRecords
+	start, summary := time.Now(), Summary{}
+	err := req.harvestRecords(ctx, &summary, func(record *Record) error {
+		return writer.Write(ctx, record)
+	})
+	err = summary.finish(start, err)
+	req.logSummary(summary, err)
+	return summary, err
+}
+
+// A RecordWriter writing each record as an XML document of its own,
+// Dir/ab/cd/identifier.xml, in two levels of subdirectories named after
+// the hash of the identifier so no directory holds too many files
+type DirWriter struct {
+	Dir string
+
+	// Remove the file of a deleted record instead of writing its header
+	RemoveDeleted bool
+}
+
+// Write the record, with its header, metadata and about, as a record
+// element, replacing the file of an earlier version
+func (dirWriter *DirWriter) Write(ctx context.Context, record *Record) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	path := dirWriter.Path(record.Header.Identifier)
+	if record.Header.IsDeleted() && dirWriter.RemoveDeleted {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	document, err := recordDocument(record)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	// Write to a temporary file first, so a reader never sees half a record
+	tmp, err := ioutil.TempFile(filepath.Dir(path), ".partial-")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(document)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// The record as an XML document, encoded in full before anything is
+// written so a failure never leaves half a record behind
+func recordDocument(record *Record) ([]byte, error) {
+	var document bytes.Buffer
+	document.WriteString(xml.Header)
+	if err := record.WriteXML(&document); err != nil {
+		return nil, fmt.Errorf("oai: encoding record %s: %w", record.Header.Identifier, err)
+	}
+	document.WriteString("\n")
+	return document.Bytes(), nil
+}
+
+// Nothing to flush: each record is complete once written
+func (dirWriter *DirWriter) Close() error {
+	return nil
+}
+
+// The path of the file of the record with the given identifier
+func (dirWriter *DirWriter) Path(identifier string) string {
+	hash := sha256.Sum256([]byte(identifier))
+	digest := hex.EncodeToString(hash[:])
+	return filepath.Join(dirWriter.Dir, digest[0:2], digest[2:4], sanitizeFilename(identifier)+".xml")
+}
+
+// The longest file name before the extension, well within the limits
+// of the common file systems
+const maxFilenameLength = 200
+
+// A file name for the identifier: the characters other than ASCII letters,
+// digits, '-', '_' and '.' are percent-encoded, as is a leading '.', so
+// distinct identifiers give distinct names on any file system; names too
+// long are cut and made unique by the hash of the identifier
+func sanitizeFilename(identifier string) string {
+	var name strings.Builder
+	for i := 0; i < len(identifier); i++ {
+		c := identifier[i]
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.' && i > 0:
+			name.WriteByte(c)
+		default:
+			fmt.Fprintf(&name, "%%%02X", c)
+		}
+	}
+
+	if name.Len() <= maxFilenameLength {
+		return name.String()
+	}
+	hash := sha256.Sum256([]byte(identifier))
+	return name.String()[:maxFilenameLength-65] + "~" + hex.EncodeToString(hash[:])
+}